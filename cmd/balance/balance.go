@@ -0,0 +1,50 @@
+package balance
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/chain"
+	"github.com/herlon214/ipfs-blockchain/pkg/config"
+	walletsPkg "github.com/herlon214/ipfs-blockchain/pkg/wallets"
+	"github.com/spf13/cobra"
+)
+
+var BalanceCmd = &cobra.Command{
+	Use:          "balance ADDR",
+	Short:        "Show the sum of the unspent outputs of an address",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		address := args[0]
+
+		pubKeyHash, err := walletsPkg.DecodeAddress([]byte(address))
+		if err != nil {
+			return fmt.Errorf("address %s: %w", address, err)
+		}
+
+		cfg, err := config.Load(cmd.Flags())
+		if err != nil {
+			return err
+		}
+
+		if !chain.Exists(cfg) {
+			return errors.New("blockchain not found")
+		}
+
+		blockChain, err := chain.New(cfg)
+		if err != nil {
+			return err
+		}
+		defer blockChain.Database.Close()
+
+		balance, err := blockChain.Balance(pubKeyHash)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Balance of %s: %d\n", address, balance)
+
+		return nil
+	},
+}
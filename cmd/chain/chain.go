@@ -0,0 +1,15 @@
+package chain
+
+import "github.com/spf13/cobra"
+
+var ChainCmd = &cobra.Command{
+	Use:   "chain",
+	Short: "Inspect the local blockchain",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+func init() {
+	ChainCmd.AddCommand(VerifyCmd)
+}
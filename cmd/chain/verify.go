@@ -0,0 +1,52 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	chainPkg "github.com/herlon214/ipfs-blockchain/pkg/chain"
+	"github.com/herlon214/ipfs-blockchain/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var VerifyCmd = &cobra.Command{
+	Use:          "verify",
+	Short:        "Verify the proof of work, linkage and transactions of every block",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load(cmd.Flags())
+		if err != nil {
+			return err
+		}
+
+		if !chainPkg.Exists(cfg) {
+			return errors.New("blockchain not found")
+		}
+
+		blockChain, err := chainPkg.New(cfg)
+		if err != nil {
+			return err
+		}
+		defer blockChain.Database.Close()
+
+		fmt.Printf("Verifying chain from %x\n", blockChain.LastHash())
+
+		err = blockChain.Verify(context.Background())
+		if err != nil {
+			var verifyErr *chainPkg.VerifyError
+			if errors.As(err, &verifyErr) {
+				fmt.Println("Invalid block found:")
+				fmt.Printf("  Hash:   %x\n", verifyErr.Hash)
+				fmt.Printf("  Height: %d\n", verifyErr.Height)
+				fmt.Printf("  Reason: %s\n", verifyErr.Err)
+			}
+
+			return err
+		}
+
+		fmt.Println("Chain is valid")
+
+		return nil
+	},
+}
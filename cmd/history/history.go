@@ -0,0 +1,92 @@
+package history
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/chain"
+	"github.com/herlon214/ipfs-blockchain/pkg/config"
+	walletsPkg "github.com/herlon214/ipfs-blockchain/pkg/wallets"
+	"github.com/spf13/cobra"
+)
+
+var (
+	limit      int
+	jsonOutput bool
+)
+
+type entry struct {
+	TxID      string `json:"txId"`
+	BlockHash string `json:"blockHash"`
+	Height    int    `json:"height"`
+	Direction string `json:"direction"`
+	Amount    int    `json:"amount"`
+}
+
+var HistoryCmd = &cobra.Command{
+	Use:          "history ADDR",
+	Short:        "List the transactions that paid to or spent from an address",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		address := args[0]
+
+		pubKeyHash, err := walletsPkg.DecodeAddress([]byte(address))
+		if err != nil {
+			return fmt.Errorf("address %s: %w", address, err)
+		}
+
+		cfg, err := config.Load(cmd.Flags())
+		if err != nil {
+			return err
+		}
+
+		if !chain.Exists(cfg) {
+			return errors.New("blockchain not found")
+		}
+
+		blockChain, err := chain.New(cfg)
+		if err != nil {
+			return err
+		}
+		defer blockChain.Database.Close()
+
+		history, err := blockChain.History(pubKeyHash, limit)
+		if err != nil {
+			return err
+		}
+
+		entries := make([]entry, 0, len(history))
+		for _, item := range history {
+			entries = append(entries, entry{
+				TxID:      hex.EncodeToString(item.TxID),
+				BlockHash: hex.EncodeToString(item.BlockHash),
+				Height:    item.Height,
+				Direction: item.Direction,
+				Amount:    item.Amount,
+			})
+		}
+
+		if jsonOutput {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+
+			return encoder.Encode(entries)
+		}
+
+		fmt.Println("Listing", len(entries), "transactions of", address)
+		for _, item := range entries {
+			fmt.Printf("--> %s %-3s %d at height %d in block %s\n", item.TxID, item.Direction, item.Amount, item.Height, item.BlockHash)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	HistoryCmd.Flags().IntVar(&limit, "limit", 0, "maximum number of transactions to list, 0 lists all")
+	HistoryCmd.Flags().BoolVar(&jsonOutput, "json", false, "print the transactions as JSON")
+}
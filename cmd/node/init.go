@@ -0,0 +1,30 @@
+package node
+
+import (
+	"fmt"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/config"
+	nodePkg "github.com/herlon214/ipfs-blockchain/pkg/node"
+	"github.com/spf13/cobra"
+)
+
+var InitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create the node key and the chain in the data directory",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load(cmd.Flags())
+		if err != nil {
+			return err
+		}
+
+		if err := nodePkg.Init(cfg); err != nil {
+			return err
+		}
+
+		fmt.Println("Node initialized in", cfg.DataDir)
+		fmt.Println("Key:", cfg.KeyPath())
+		fmt.Println("Chain:", cfg.ChainDir())
+
+		return nil
+	},
+}
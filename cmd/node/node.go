@@ -0,0 +1,16 @@
+package node
+
+import "github.com/spf13/cobra"
+
+var NodeCmd = &cobra.Command{
+	Use:   "node",
+	Short: "Run a blockchain node",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+func init() {
+	NodeCmd.AddCommand(InitCmd)
+	NodeCmd.AddCommand(StartCmd)
+}
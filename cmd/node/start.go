@@ -0,0 +1,39 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"syscall"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/config"
+	nodePkg "github.com/herlon214/ipfs-blockchain/pkg/node"
+	"github.com/spf13/cobra"
+)
+
+var StartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start the node and run it until interrupted",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load(cmd.Flags())
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		fmt.Println("Starting node...")
+		n, err := nodePkg.Start(ctx, cfg)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("Node started, press Ctrl+C to stop")
+		<-ctx.Done()
+
+		fmt.Println("Shutting down...")
+
+		return n.Close()
+	},
+}
@@ -1,17 +1,33 @@
 package cmd
 
 import (
+	"github.com/herlon214/ipfs-blockchain/cmd/balance"
+	"github.com/herlon214/ipfs-blockchain/cmd/chain"
+	"github.com/herlon214/ipfs-blockchain/cmd/history"
+	"github.com/herlon214/ipfs-blockchain/cmd/node"
+	"github.com/herlon214/ipfs-blockchain/cmd/send"
+	"github.com/herlon214/ipfs-blockchain/cmd/tx"
 	"github.com/herlon214/ipfs-blockchain/cmd/wallets"
+	"github.com/herlon214/ipfs-blockchain/pkg/config"
 	"github.com/spf13/cobra"
 )
 
 var RootCmd = &cobra.Command{
-	Use: "blockchain",
+	Use:           "blockchain",
+	SilenceErrors: true,
 	Run: func(cmd *cobra.Command, args []string) {
 		cmd.Help()
 	},
 }
 
 func init() {
+	config.AddFlags(RootCmd.PersistentFlags())
+
 	RootCmd.AddCommand(wallets.WalletsCmd)
+	RootCmd.AddCommand(send.SendCmd)
+	RootCmd.AddCommand(balance.BalanceCmd)
+	RootCmd.AddCommand(history.HistoryCmd)
+	RootCmd.AddCommand(chain.ChainCmd)
+	RootCmd.AddCommand(tx.TxCmd)
+	RootCmd.AddCommand(node.NodeCmd)
 }
@@ -0,0 +1,158 @@
+package send
+
+import (
+	"errors"
+	"fmt"
+
+	walletsCmd "github.com/herlon214/ipfs-blockchain/cmd/wallets"
+	"github.com/herlon214/ipfs-blockchain/pkg/api"
+	"github.com/herlon214/ipfs-blockchain/pkg/chain"
+	"github.com/herlon214/ipfs-blockchain/pkg/config"
+	"github.com/herlon214/ipfs-blockchain/pkg/node"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+	walletsPkg "github.com/herlon214/ipfs-blockchain/pkg/wallets"
+	"github.com/spf13/cobra"
+)
+
+var (
+	walletFile string
+	from       string
+	to         string
+	amount     int
+	fee        int
+	nodeURL    string
+)
+
+var SendCmd = &cobra.Command{
+	Use:   "send",
+	Short: "Send coins from one of the wallet addresses to another address",
+	Long: `Send coins from one of the wallet addresses to another address.
+
+With --node the transaction is submitted to the mempool of a running node
+through its API, otherwise it is mined right away in the local chain.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := walletsPkg.DecodeAddress([]byte(to)); err != nil {
+			return fmt.Errorf("recipient %s: %w", to, err)
+		}
+
+		ws, err := walletsCmd.Load(walletFile, walletsCmd.Passphrase)
+		if err != nil {
+			return err
+		}
+
+		wallet := ws.Wallet(from)
+		if wallet == nil {
+			return fmt.Errorf("unknown sender address %s in wallet %s", from, walletFile)
+		}
+
+		cfg, err := config.Load(cmd.Flags())
+		if err != nil {
+			return err
+		}
+
+		if nodeURL != "" {
+			return submit(api.NewClient(nodeURL, cfg.API.Token), wallet)
+		}
+
+		return mine(cfg, wallet)
+	},
+}
+
+// submit sends the transaction to a running node, leaving out the outputs
+// already spent by its mempool.
+func submit(client *api.Client, wallet *walletsPkg.Wallet) error {
+	utxos, err := client.UTXOs(from)
+	if err != nil {
+		return err
+	}
+
+	pool, err := client.Mempool()
+	if err != nil {
+		return err
+	}
+
+	spent := make(map[string]bool)
+	for _, tx := range pool.Transactions {
+		for _, in := range tx.Inputs {
+			spent[fmt.Sprintf("%s:%d", in.TxID, in.Out)] = true
+		}
+	}
+
+	available := utxos[:0]
+	for _, utxo := range utxos {
+		if !spent[fmt.Sprintf("%x:%d", utxo.TxID, utxo.Index)] {
+			available = append(available, utxo)
+		}
+	}
+
+	tx, err := chain.BuildTransaction(wallet, available, to, amount, fee)
+	if err != nil {
+		if errors.Is(err, chain.ErrInsufficientFunds) {
+			return fmt.Errorf("address %s: %w", from, err)
+		}
+
+		return err
+	}
+
+	if err := client.Submit(tx); err != nil {
+		return err
+	}
+
+	fmt.Printf("Submitted %d coins from %s to %s\n", amount, from, to)
+	fmt.Printf("Transaction: %x\n", tx.ID)
+
+	return nil
+}
+
+// mine mines the transaction in the local chain, the sender collects the
+// block reward.
+func mine(cfg *config.Config, wallet *walletsPkg.Wallet) error {
+	if !chain.Exists(cfg) {
+		return fmt.Errorf("%w: no chain in %s", node.ErrNotInitialized, cfg.ChainDir())
+	}
+
+	blockChain, err := chain.New(cfg)
+	if err != nil {
+		return err
+	}
+	defer blockChain.Database.Close()
+
+	tx, err := blockChain.NewTransaction(wallet, to, amount, fee)
+	if err != nil {
+		if errors.Is(err, chain.ErrInsufficientFunds) {
+			return fmt.Errorf("address %s: %w", from, err)
+		}
+
+		return err
+	}
+
+	coinbaseTx, err := transaction.CoinBase(from, "", fee)
+	if err != nil {
+		return err
+	}
+
+	newBlock, err := blockChain.AddBlock([]*transaction.Transaction{coinbaseTx, tx})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Sent %d coins from %s to %s\n", amount, from, to)
+	fmt.Printf("Transaction: %x\n", tx.ID)
+	fmt.Printf("Block: %x\n", newBlock.Hash)
+
+	return nil
+}
+
+func init() {
+	SendCmd.Flags().StringVarP(&walletFile, "file", "f", "", "wallet path")
+	SendCmd.Flags().StringVar(&from, "from", "", "sender address")
+	SendCmd.Flags().StringVar(&to, "to", "", "recipient address")
+	SendCmd.Flags().IntVar(&amount, "amount", 0, "amount of coins to send")
+	SendCmd.Flags().IntVar(&fee, "fee", 0, "fee paid to the miner")
+	SendCmd.Flags().StringVar(&nodeURL, "node", "", "API URL of a running node to submit the transaction to, like http://127.0.0.1:4080")
+
+	SendCmd.MarkFlagRequired("from")
+	SendCmd.MarkFlagRequired("to")
+	SendCmd.MarkFlagRequired("amount")
+}
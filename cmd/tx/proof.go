@@ -0,0 +1,112 @@
+package tx
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/chain"
+	"github.com/herlon214/ipfs-blockchain/pkg/config"
+	"github.com/herlon214/ipfs-blockchain/pkg/merkle"
+	"github.com/spf13/cobra"
+)
+
+var jsonOutput bool
+
+type proofStep struct {
+	Hash string `json:"hash"`
+	Left bool   `json:"left"`
+}
+
+type proof struct {
+	TxID       string      `json:"txId"`
+	BlockHash  string      `json:"blockHash"`
+	Height     uint64      `json:"height"`
+	MerkleRoot string      `json:"merkleRoot"`
+	Path       []proofStep `json:"path"`
+}
+
+var ProofCmd = &cobra.Command{
+	Use:          "proof TXID",
+	Short:        "Print the merkle proof that a transaction is included in a block",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		txID, err := hex.DecodeString(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid transaction ID %s: %w", args[0], err)
+		}
+
+		cfg, err := config.Load(cmd.Flags())
+		if err != nil {
+			return err
+		}
+
+		if !chain.Exists(cfg) {
+			return errors.New("blockchain not found")
+		}
+
+		blockChain, err := chain.New(cfg)
+		if err != nil {
+			return err
+		}
+		defer blockChain.Database.Close()
+
+		b, err := blockChain.FindTransactionBlock(txID)
+		if err != nil {
+			return err
+		}
+
+		path, err := b.TransactionProof(txID)
+		if err != nil {
+			return err
+		}
+
+		if !merkle.VerifyProof(b.MerkleRoot, txID, path) {
+			return fmt.Errorf("proof of %x does not match the merkle root of block %x", txID, b.Hash)
+		}
+
+		result := proof{
+			TxID:       hex.EncodeToString(txID),
+			BlockHash:  hex.EncodeToString(b.Hash),
+			Height:     b.Height,
+			MerkleRoot: hex.EncodeToString(b.MerkleRoot),
+		}
+
+		for _, step := range path {
+			result.Path = append(result.Path, proofStep{
+				Hash: hex.EncodeToString(step.Hash),
+				Left: step.Left,
+			})
+		}
+
+		if jsonOutput {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+
+			return encoder.Encode(result)
+		}
+
+		fmt.Println("Transaction:", result.TxID)
+		fmt.Println("Block:", result.BlockHash)
+		fmt.Println("Height:", result.Height)
+		fmt.Println("Merkle root:", result.MerkleRoot)
+		fmt.Println("Path:")
+		for _, step := range result.Path {
+			side := "right"
+			if step.Left {
+				side = "left"
+			}
+
+			fmt.Printf("--> %-5s %s\n", side, step.Hash)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	ProofCmd.Flags().BoolVar(&jsonOutput, "json", false, "print the proof as JSON")
+}
@@ -0,0 +1,15 @@
+package tx
+
+import "github.com/spf13/cobra"
+
+var TxCmd = &cobra.Command{
+	Use:   "tx",
+	Short: "Deal with transactions",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+func init() {
+	TxCmd.AddCommand(ProofCmd)
+}
@@ -0,0 +1,67 @@
+package wallets
+
+import (
+	"fmt"
+	"os"
+
+	walletsPkg "github.com/herlon214/ipfs-blockchain/pkg/wallets"
+	"github.com/spf13/cobra"
+)
+
+var (
+	keyFormat string
+	keyOutput string
+)
+
+var ExportCmd = &cobra.Command{
+	Use:          "export ADDR",
+	Short:        "Export the private key of an address as PEM",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ws, err := Load(walletFile, Passphrase)
+		if err != nil {
+			return err
+		}
+
+		wallet := ws.Wallet(args[0])
+		if wallet == nil {
+			return fmt.Errorf("address %s not found in %s", args[0], walletFile)
+		}
+
+		data, err := wallet.ExportPEM(keyFormat)
+		if err != nil {
+			return err
+		}
+
+		if keyOutput == "" {
+			fmt.Fprintln(os.Stderr, "Warning: anyone reading this key can spend the funds of", args[0])
+			_, err = os.Stdout.Write(data)
+
+			return err
+		}
+
+		f, err := os.OpenFile(keyOutput, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return err
+		}
+
+		if _, err := f.Write(data); err != nil {
+			f.Close()
+			return err
+		}
+
+		if err := f.Close(); err != nil {
+			return err
+		}
+
+		fmt.Println("Private key of", args[0], "written to", keyOutput)
+
+		return nil
+	},
+}
+
+func init() {
+	ExportCmd.Flags().StringVar(&keyFormat, "format", walletsPkg.FormatPKCS8, "key format, pkcs8 or sec1")
+	ExportCmd.Flags().StringVarP(&keyOutput, "output", "o", "", "file to write the key to, created with owner only permissions, stdout by default")
+}
@@ -0,0 +1,52 @@
+package wallets
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	walletsPkg "github.com/herlon214/ipfs-blockchain/pkg/wallets"
+	"github.com/spf13/cobra"
+)
+
+var ImportCmd = &cobra.Command{
+	Use:          "import FILE",
+	Short:        "Import a PKCS #8 or SEC 1 PEM private key, - reads it from stdin",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var data []byte
+		var err error
+
+		if args[0] == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			return err
+		}
+
+		wallet, err := walletsPkg.ImportPEM(data)
+		if err != nil {
+			return err
+		}
+
+		ws, err := Load(walletFile, Passphrase)
+		if err != nil {
+			return err
+		}
+
+		if err := ws.Import(wallet); err != nil {
+			return err
+		}
+
+		if err := ws.Save(); err != nil {
+			return err
+		}
+
+		fmt.Println("Address imported:", string(wallet.Address()))
+
+		return nil
+	},
+}
@@ -3,23 +3,61 @@ package wallets
 import (
 	"fmt"
 
-	walletsPkg "github.com/herlon214/ipfs-blockchain/pkg/wallets"
+	"github.com/herlon214/ipfs-blockchain/pkg/chain"
+	"github.com/herlon214/ipfs-blockchain/pkg/config"
 	"github.com/spf13/cobra"
 )
 
+var showBalance bool
+
 var ListCmd = &cobra.Command{
 	Use:   "ls",
 	Short: "List wallets addresses",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		fmt.Println("Loading wallet from file", walletFile)
-		ws, err := walletsPkg.Load(walletFile)
+		ws, err := Load(walletFile, Passphrase)
 		if err != nil {
-			panic(err)
+			return err
+		}
+
+		var blockChain *chain.Chain
+		if showBalance {
+			cfg, err := config.Load(cmd.Flags())
+			if err != nil {
+				return err
+			}
+
+			if chain.Exists(cfg) {
+				blockChain, err = chain.New(cfg)
+				if err != nil {
+					return err
+				}
+				defer blockChain.Database.Close()
+			}
 		}
 
 		fmt.Println("Listing", len(ws.Items), "addresses:")
 		for _, wallet := range ws.Items {
-			fmt.Println("-->", string(wallet.Address()))
+			if !showBalance {
+				fmt.Println("-->", string(wallet.Address()))
+				continue
+			}
+
+			balance := 0
+			if blockChain != nil {
+				balance, err = blockChain.Balance(wallet.PublicKeyHash())
+				if err != nil {
+					return err
+				}
+			}
+
+			fmt.Println("-->", string(wallet.Address()), balance)
 		}
+
+		return nil
 	},
 }
+
+func init() {
+	ListCmd.Flags().BoolVarP(&showBalance, "balance", "b", false, "show the balance of each address")
+}
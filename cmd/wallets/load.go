@@ -0,0 +1,23 @@
+package wallets
+
+import (
+	"fmt"
+	"os"
+
+	walletsPkg "github.com/herlon214/ipfs-blockchain/pkg/wallets"
+)
+
+// Load reads the wallet file like wallets.Load and warns about the
+// addresses changed by the migration of a gob file.
+func Load(filePath string, passphrase walletsPkg.PassphraseFunc) (*walletsPkg.Wallets, error) {
+	ws, err := walletsPkg.Load(filePath, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	for old, current := range ws.Renamed {
+		fmt.Fprintf(os.Stderr, "Warning: address %s of %s is now %s, the coins sent to the old address cannot be spent\n", old, filePath, current)
+	}
+
+	return ws, nil
+}
@@ -0,0 +1,99 @@
+package wallets
+
+import (
+	"fmt"
+
+	walletsPkg "github.com/herlon214/ipfs-blockchain/pkg/wallets"
+	"github.com/spf13/cobra"
+)
+
+var LockCmd = &cobra.Command{
+	Use:          "lock",
+	Short:        "Encrypt the wallet file with a passphrase",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Loading an encrypted file without its passphrase fails, check first
+		encrypted, err := walletsPkg.IsEncrypted(walletFile)
+		if err != nil {
+			return err
+		}
+
+		if encrypted {
+			return fmt.Errorf("%s is already encrypted, use change-passphrase", walletFile)
+		}
+
+		ws, err := Load(walletFile, nil)
+		if err != nil {
+			return err
+		}
+
+		passphrase, err := newPassphrase()
+		if err != nil {
+			return err
+		}
+
+		ws.SetPassphrase(passphrase)
+		if err := ws.Save(); err != nil {
+			return err
+		}
+
+		fmt.Println("Wallet encrypted")
+
+		return nil
+	},
+}
+
+var UnlockCmd = &cobra.Command{
+	Use:          "unlock",
+	Short:        "Remove the encryption of the wallet file, leaving the keys in clear",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ws, err := Load(walletFile, Passphrase)
+		if err != nil {
+			return err
+		}
+
+		if !ws.Encrypted() {
+			return fmt.Errorf("%s: %w", walletFile, walletsPkg.ErrNotEncrypted)
+		}
+
+		ws.SetPassphrase(nil)
+		if err := ws.Save(); err != nil {
+			return err
+		}
+
+		fmt.Println("Wallet decrypted, the private keys are now stored in clear")
+
+		return nil
+	},
+}
+
+var ChangePassphraseCmd = &cobra.Command{
+	Use:          "change-passphrase",
+	Short:        "Encrypt the wallet file with a new passphrase",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ws, err := Load(walletFile, Passphrase)
+		if err != nil {
+			return err
+		}
+
+		if !ws.Encrypted() {
+			return fmt.Errorf("%s: %w, use lock", walletFile, walletsPkg.ErrNotEncrypted)
+		}
+
+		passphrase, err := newPassphrase()
+		if err != nil {
+			return err
+		}
+
+		ws.SetPassphrase(passphrase)
+		if err := ws.Save(); err != nil {
+			return err
+		}
+
+		fmt.Println("Passphrase changed")
+
+		return nil
+	},
+}
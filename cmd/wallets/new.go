@@ -10,22 +10,42 @@ import (
 var NewWallet = &cobra.Command{
 	Use:   "new",
 	Short: "Create a new wallet",
-	Run: func(cmd *cobra.Command, args []string) {
-		ws, err := walletsPkg.New(walletFile)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Checked before asking for the passphrase, NewHD checks it again
+		if err := walletsPkg.CheckNotExists(walletFile); err != nil {
+			return err
+		}
+
+		passphrase, err := newPassphrase()
 		if err != nil {
-			panic(err)
+			return err
 		}
 
-		defer ws.Save()
+		mnemonic, err := walletsPkg.NewMnemonic(walletsPkg.DefaultEntropyBits)
+		if err != nil {
+			return err
+		}
+
+		ws, err := walletsPkg.NewHD(walletFile, mnemonic, passphrase)
+		if err != nil {
+			return err
+		}
 
 		fmt.Println("Wallet created!")
+		fmt.Println()
+		fmt.Println("Write down the recovery phrase below and keep it safe, it restores every")
+		fmt.Println("address of the wallet with `wallets restore`:")
+		fmt.Println()
+		fmt.Println("   ", mnemonic)
+		fmt.Println()
 
 		wallet, err := ws.NewWallet()
 		if err != nil {
-			panic(err)
+			return err
 		}
 
 		fmt.Println("Initial address created:", string(wallet.Address()))
 
+		return ws.Save()
 	},
 }
@@ -3,27 +3,29 @@ package wallets
 import (
 	"fmt"
 
-	walletsPkg "github.com/herlon214/ipfs-blockchain/pkg/wallets"
 	"github.com/spf13/cobra"
 )
 
 var NewAddrCmd = &cobra.Command{
 	Use:   "newaddr",
 	Short: "Create a new wallet address",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		fmt.Println("Loading wallet from file", walletFile)
-		ws, err := walletsPkg.Load(walletFile)
+		ws, err := Load(walletFile, Passphrase)
 		if err != nil {
-			panic(err)
+			return err
 		}
 
-		defer ws.Save()
-
 		wallet, err := ws.NewWallet()
 		if err != nil {
-			panic(err)
+			return err
 		}
 
 		fmt.Println("Address created:", string(wallet.Address()))
+		if wallet.Path != "" {
+			fmt.Println("Derivation path:", wallet.Path)
+		}
+
+		return ws.Save()
 	},
 }
@@ -0,0 +1,21 @@
+package wallets
+
+import "github.com/herlon214/ipfs-blockchain/pkg/prompt"
+
+const (
+	// PassphraseEnv holds the wallet passphrase, skipping the prompt
+	PassphraseEnv = "BLOCKCHAIN_WALLET_PASSPHRASE"
+	// NewPassphraseEnv holds the passphrase set by new, lock and
+	// change-passphrase, skipping the prompt
+	NewPassphraseEnv = "BLOCKCHAIN_WALLET_NEW_PASSPHRASE"
+)
+
+// Passphrase asks for the passphrase of an encrypted wallet file, it is
+// meant to be given to wallets.Load.
+func Passphrase() ([]byte, error) {
+	return prompt.Passphrase(PassphraseEnv, "Wallet passphrase")
+}
+
+func newPassphrase() ([]byte, error) {
+	return prompt.NewPassphrase(NewPassphraseEnv, "New wallet passphrase")
+}
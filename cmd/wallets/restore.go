@@ -0,0 +1,77 @@
+package wallets
+
+import (
+	"fmt"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/chain"
+	"github.com/herlon214/ipfs-blockchain/pkg/config"
+	walletsPkg "github.com/herlon214/ipfs-blockchain/pkg/wallets"
+	"github.com/spf13/cobra"
+)
+
+var mnemonic string
+
+var RestoreCmd = &cobra.Command{
+	Use:          "restore",
+	Short:        "Restore a wallet from its recovery phrase, looking for the used addresses in the chain",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := walletsPkg.NormalizeMnemonic(mnemonic); err != nil {
+			return err
+		}
+
+		if err := walletsPkg.CheckNotExists(walletFile); err != nil {
+			return err
+		}
+
+		cfg, err := config.Load(cmd.Flags())
+		if err != nil {
+			return err
+		}
+
+		used := make(map[string]bool)
+		if chain.Exists(cfg) {
+			blockChain, err := chain.New(cfg)
+			if err != nil {
+				return err
+			}
+
+			used, err = blockChain.UsedKeys()
+			blockChain.Database.Close()
+			if err != nil {
+				return err
+			}
+		} else {
+			fmt.Println("Blockchain not found, restoring without looking for used addresses")
+		}
+
+		passphrase, err := newPassphrase()
+		if err != nil {
+			return err
+		}
+
+		ws, err := walletsPkg.Restore(walletFile, mnemonic, passphrase, func(pubKeyHash []byte) bool {
+			return used[string(pubKeyHash)]
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("Wallet restored with", len(ws.Items), "addresses:")
+		for index := uint32(0); index < ws.NextIndex; index++ {
+			wallet, err := walletsPkg.DeriveWallet(ws.Seed, walletsPkg.AddressPath(index))
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("-->", string(wallet.Address()), wallet.Path)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	RestoreCmd.Flags().StringVar(&mnemonic, "mnemonic", "", "recovery phrase of the wallet")
+	RestoreCmd.MarkFlagRequired("mnemonic")
+}
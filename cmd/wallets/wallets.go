@@ -20,4 +20,10 @@ func init() {
 	WalletsCmd.AddCommand(NewWallet)
 	WalletsCmd.AddCommand(ListCmd)
 	WalletsCmd.AddCommand(NewAddrCmd)
+	WalletsCmd.AddCommand(RestoreCmd)
+	WalletsCmd.AddCommand(ExportCmd)
+	WalletsCmd.AddCommand(ImportCmd)
+	WalletsCmd.AddCommand(LockCmd)
+	WalletsCmd.AddCommand(UnlockCmd)
+	WalletsCmd.AddCommand(ChangePassphraseCmd)
 }
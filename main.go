@@ -29,9 +29,9 @@ func main() {
 func herlonWallet() {
 	// Load wallet
 	walletFile := "herlon_wallet.dat"
-	ws, err := wallets.Load(walletFile)
+	ws, err := wallets.Load(walletFile, nil)
 	if err != nil {
-		ws, err = wallets.New(walletFile)
+		ws, err = wallets.New(walletFile, nil)
 		if err != nil {
 			panic(err)
 		}
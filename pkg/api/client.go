@@ -0,0 +1,139 @@
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/chain"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+	"github.com/herlon214/ipfs-blockchain/pkg/wallets"
+)
+
+// clientTimeout bounds every request of the client
+const clientTimeout = 30 * time.Second
+
+// StatusError is an error response of the API.
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("api: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Client calls the API of a running node.
+type Client struct {
+	// URL is the base URL of the API, like http://127.0.0.1:4080
+	URL string
+	// Token is sent as a bearer token when set
+	Token string
+	HTTP  *http.Client
+}
+
+func NewClient(baseURL string, token string) *Client {
+	return &Client{
+		URL:   strings.TrimRight(baseURL, "/"),
+		Token: token,
+		HTTP:  &http.Client{Timeout: clientTimeout},
+	}
+}
+
+// UTXOs returns the unspent outputs of the address in the best chain.
+func (c *Client) UTXOs(address string) ([]chain.UTXO, error) {
+	pubKeyHash, err := wallets.DecodeAddress([]byte(address))
+	if err != nil {
+		return nil, err
+	}
+
+	var utxos []UTXO
+	if err := c.do(http.MethodGet, "/addresses/"+url.PathEscape(address)+"/utxos", nil, &utxos); err != nil {
+		return nil, err
+	}
+
+	result := make([]chain.UTXO, 0, len(utxos))
+	for _, utxo := range utxos {
+		txID, err := hex.DecodeString(utxo.TxID)
+		if err != nil {
+			return nil, fmt.Errorf("api: utxo %q: %w", utxo.TxID, err)
+		}
+
+		result = append(result, chain.UTXO{
+			TxID:   txID,
+			Index:  utxo.Index,
+			Output: transaction.Output{Value: utxo.Value, PubKeyHash: pubKeyHash},
+		})
+	}
+
+	return result, nil
+}
+
+// Mempool returns the transactions waiting to be mined.
+func (c *Client) Mempool() (*Mempool, error) {
+	var pool Mempool
+	if err := c.do(http.MethodGet, "/mempool", nil, &pool); err != nil {
+		return nil, err
+	}
+
+	return &pool, nil
+}
+
+// Submit sends a signed transaction to the node, which admits it to its
+// mempool and relays it to its peers.
+func (c *Client) Submit(tx *transaction.Transaction) error {
+	data, err := tx.Serialize()
+	if err != nil {
+		return err
+	}
+
+	return c.do(http.MethodPost, "/txs", SubmitRequest{Tx: hex.EncodeToString(data)}, &SubmitResponse{})
+}
+
+func (c *Client) do(method string, path string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.URL+path, reader)
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var apiErr Error
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil {
+			apiErr.Error = resp.Status
+		}
+
+		return &StatusError{StatusCode: resp.StatusCode, Message: apiErr.Error}
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,370 @@
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+	"github.com/herlon214/ipfs-blockchain/pkg/chain"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+	"github.com/herlon214/ipfs-blockchain/pkg/wallets"
+)
+
+const (
+	// DefaultBufferSize is the number of events queued for a subscriber
+	// before it is dropped as a slow consumer
+	DefaultBufferSize = 256
+	// heartbeatInterval is how often an idle stream gets a comment line, so
+	// proxies do not close it
+	heartbeatInterval = 15 * time.Second
+)
+
+// Event types sent on the stream.
+const (
+	EventTip               = "tip"
+	EventBlockConnected    = "blockConnected"
+	EventBlockDisconnected = "blockDisconnected"
+	EventTransaction       = "tx"
+	EventPayment           = "payment"
+)
+
+var eventTypes = []string{EventTip, EventBlockConnected, EventBlockDisconnected, EventTransaction, EventPayment}
+
+var (
+	ErrSlowConsumer = errors.New("subscriber is not keeping up with the events")
+	ErrHubClosed    = errors.New("event hub closed")
+)
+
+// Event is a single message of the stream.
+type Event struct {
+	Type string
+	Data interface{}
+}
+
+// Payment is an output paying to a subscribed address, sent once when its
+// transaction enters the mempool and again when its block is connected.
+type Payment struct {
+	Address   string `json:"address"`
+	TxID      string `json:"txId"`
+	Index     int    `json:"index"`
+	Value     int    `json:"value"`
+	Confirmed bool   `json:"confirmed"`
+	BlockHash string `json:"blockHash,omitempty"`
+	Height    uint64 `json:"height,omitempty"`
+}
+
+// Filter selects the events a subscriber receives.
+type Filter struct {
+	// Types holds the event types wanted, empty means every type
+	Types map[string]bool
+	// Addresses holds the addresses whose payments are wanted
+	Addresses map[string]bool
+}
+
+func (f Filter) wants(e Event) bool {
+	if len(f.Types) > 0 && !f.Types[e.Type] {
+		return false
+	}
+
+	if p, ok := e.Data.(Payment); ok {
+		return f.Addresses[p.Address]
+	}
+
+	return true
+}
+
+// Subscriber receives the events matching its filter until it is dropped or
+// unsubscribed.
+type Subscriber struct {
+	id     int
+	filter Filter
+	events chan Event
+	done   chan struct{}
+	err    error
+}
+
+// Events returns the events queued for the subscriber.
+func (s *Subscriber) Events() <-chan Event {
+	return s.events
+}
+
+// Done is closed once the subscriber is dropped or unsubscribed.
+func (s *Subscriber) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns why the subscriber was dropped, once Done is closed.
+func (s *Subscriber) Err() error {
+	return s.err
+}
+
+// Hub fans the chain and mempool events out to the stream subscribers.
+// Publishing never blocks: subscribers whose buffer is full are dropped.
+type Hub struct {
+	Chain *chain.Chain
+	// BufferSize is the queue length of the new subscribers
+	BufferSize int
+
+	mx          sync.Mutex
+	subscribers map[int]*Subscriber
+	next        int
+	closed      bool
+}
+
+func NewHub(c *chain.Chain) *Hub {
+	return &Hub{
+		Chain:       c,
+		BufferSize:  DefaultBufferSize,
+		subscribers: make(map[int]*Subscriber),
+	}
+}
+
+func (h *Hub) Subscribe(f Filter) (*Subscriber, error) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	if h.closed {
+		return nil, ErrHubClosed
+	}
+
+	s := &Subscriber{
+		id:     h.next,
+		filter: f,
+		events: make(chan Event, h.BufferSize),
+		done:   make(chan struct{}),
+	}
+
+	h.subscribers[s.id] = s
+	h.next++
+
+	return s, nil
+}
+
+func (h *Hub) Unsubscribe(s *Subscriber) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	h.drop(s, nil)
+}
+
+// Close drops every subscriber and rejects the new ones.
+func (h *Hub) Close() {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	h.closed = true
+
+	for _, s := range h.subscribers {
+		h.drop(s, ErrHubClosed)
+	}
+}
+
+// Publish queues the event for the subscribers wanting it.
+func (h *Hub) Publish(e Event) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	for _, s := range h.subscribers {
+		if !s.filter.wants(e) {
+			continue
+		}
+
+		select {
+		case s.events <- e:
+		default:
+			h.drop(s, fmt.Errorf("%w: %d events queued", ErrSlowConsumer, len(s.events)))
+		}
+	}
+}
+
+func (h *Hub) drop(s *Subscriber, err error) {
+	if _, ok := h.subscribers[s.id]; !ok {
+		return
+	}
+
+	delete(h.subscribers, s.id)
+	s.err = err
+	close(s.done)
+}
+
+// ChainEvent publishes the blocks connected and disconnected by the chain,
+// along with the new tip and the payments they confirm. It is meant to be
+// given to chain.Subscribe.
+func (h *Hub) ChainEvent(e chain.Event) {
+	switch e.Type {
+	case chain.BlockConnected:
+		h.Publish(Event{Type: EventBlockConnected, Data: NewBlock(e.Block)})
+
+		for _, tx := range e.Block.Transactions {
+			h.publishPayments(tx, e.Block)
+		}
+
+		// Blocks are connected oldest first, the tip is the last one
+		if bytes.Equal(e.Block.Hash, h.Chain.LastHash()) {
+			tip, err := h.Chain.Tip()
+			if err == nil {
+				h.Publish(Event{Type: EventTip, Data: NewTip(tip)})
+			}
+		}
+	case chain.BlockDisconnected:
+		h.Publish(Event{Type: EventBlockDisconnected, Data: NewBlock(e.Block)})
+	}
+}
+
+// TransactionAdded publishes a transaction admitted to the mempool and its
+// unconfirmed payments. It is meant to be set as the pool OnAdd callback.
+func (h *Hub) TransactionAdded(tx *transaction.Transaction) {
+	h.Publish(Event{Type: EventTransaction, Data: NewTransaction(tx)})
+	h.publishPayments(tx, nil)
+}
+
+// publishPayments publishes the outputs of tx, confirmed when b is not nil.
+func (h *Hub) publishPayments(tx *transaction.Transaction, b *block.Block) {
+	for idx, out := range tx.Outputs {
+		p := Payment{
+			Address: string(wallets.EncodeAddress(out.PubKeyHash)),
+			TxID:    hex.EncodeToString(tx.ID),
+			Index:   idx,
+			Value:   out.Value,
+		}
+
+		if b != nil {
+			p.Confirmed = true
+			p.BlockHash = hex.EncodeToString(b.Hash)
+			p.Height = b.Height
+		}
+
+		h.Publish(Event{Type: EventPayment, Data: p})
+	}
+}
+
+// GET /events?types=tip,tx&address=ADDR
+//
+// Streams the events as server-sent events. The types parameter selects the
+// event types, every type by default, and each address parameter subscribes
+// to the payments to that address. Subscribers falling behind get an error
+// event with the reason before the stream is closed.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if s.Events == nil {
+		writeError(w, http.StatusNotFound, errors.New("event stream disabled"))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+
+	filter, err := parseFilter(r)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	sub, err := s.Events.Subscribe(filter)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	defer s.Events.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-sub.Done():
+			// Deliver what was queued before the drop, then the reason
+			for len(sub.Events()) > 0 {
+				if err := writeEvent(w, <-sub.Events()); err != nil {
+					return
+				}
+			}
+
+			if sub.Err() != nil {
+				writeEvent(w, Event{Type: "error", Data: Error{Error: sub.Err().Error()}})
+				flusher.Flush()
+			}
+
+			return
+		case e := <-sub.Events():
+			err = writeEvent(w, e)
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+		}
+
+		if err != nil {
+			return
+		}
+
+		flusher.Flush()
+	}
+}
+
+func parseFilter(r *http.Request) (Filter, error) {
+	filter := Filter{
+		Types:     make(map[string]bool),
+		Addresses: make(map[string]bool),
+	}
+
+	query := r.URL.Query()
+
+	for _, value := range query["types"] {
+		for _, t := range strings.Split(value, ",") {
+			if !validEventType(t) {
+				return Filter{}, fmt.Errorf("%w: unknown event type %q", ErrBadRequest, t)
+			}
+
+			filter.Types[t] = true
+		}
+	}
+
+	for _, address := range query["address"] {
+		if !wallets.ValidateAddress([]byte(address)) {
+			return Filter{}, fmt.Errorf("%w: invalid address %s", ErrBadRequest, address)
+		}
+
+		filter.Addresses[address] = true
+	}
+
+	return filter, nil
+}
+
+func validEventType(t string) bool {
+	for _, valid := range eventTypes {
+		if t == valid {
+			return true
+		}
+	}
+
+	return false
+}
+
+func writeEvent(w http.ResponseWriter, e Event) error {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+
+	return err
+}
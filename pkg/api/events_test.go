@@ -0,0 +1,195 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+	"github.com/herlon214/ipfs-blockchain/pkg/wallets"
+)
+
+// received returns the types of the events queued for the subscriber,
+// along with the address and confirmation of the payments.
+func received(s *Subscriber) []string {
+	var types []string
+
+	for len(s.Events()) > 0 {
+		e := <-s.Events()
+
+		if p, ok := e.Data.(Payment); ok {
+			types = append(types, fmt.Sprintf("%s %s %v", e.Type, p.Address, p.Confirmed))
+			continue
+		}
+
+		types = append(types, e.Type)
+	}
+
+	return types
+}
+
+func TestHubFilters(t *testing.T) {
+	ts := newTestServer(t, "")
+
+	hub := NewHub(ts.chain)
+	ts.chain.Subscribe(hub.ChainEvent)
+	ts.pool.OnAdd = hub.TransactionAdded
+
+	tx := ts.payment(t, 10)
+	to := string(wallets.EncodeAddress(tx.Outputs[0].PubKeyHash))
+
+	subscribe := func(f Filter) *Subscriber {
+		s, err := hub.Subscribe(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return s
+	}
+
+	all := subscribe(Filter{})
+	txs := subscribe(Filter{Types: map[string]bool{EventTransaction: true}})
+	payments := subscribe(Filter{Addresses: map[string]bool{to: true}})
+	blocks := subscribe(Filter{Types: map[string]bool{EventBlockConnected: true, EventTip: true}})
+
+	if err := ts.pool.Add(tx); err != nil {
+		t.Fatal(err)
+	}
+
+	coinbaseTx, err := transaction.CoinBase(string(ts.wallet.Address()), "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ts.chain.AddBlock([]*transaction.Transaction{coinbaseTx, tx}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		s    *Subscriber
+		want []string
+	}{
+		{"every type, no address", all, []string{EventTransaction, EventBlockConnected, EventTip}},
+		{"transactions", txs, []string{EventTransaction}},
+		{"payments to an address", payments, []string{
+			EventTransaction,
+			fmt.Sprintf("%s %s false", EventPayment, to),
+			EventBlockConnected,
+			fmt.Sprintf("%s %s true", EventPayment, to),
+			EventTip,
+		}},
+		{"blocks", blocks, []string{EventBlockConnected, EventTip}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := received(tt.s); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("events %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHubDropsSlowConsumer(t *testing.T) {
+	ts := newTestServer(t, "")
+
+	hub := NewHub(ts.chain)
+	hub.BufferSize = 2
+
+	slow, err := hub.Subscribe(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fast, err := hub.Subscribe(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Publishing never waits for the subscribers, the slow one is dropped
+	// once its buffer is full while the fast one keeps up
+	for i := 0; i < 3; i++ {
+		hub.Publish(Event{Type: EventTip})
+
+		<-fast.Events()
+	}
+
+	select {
+	case <-slow.Done():
+	default:
+		t.Fatal("slow subscriber not dropped")
+	}
+
+	if !errors.Is(slow.Err(), ErrSlowConsumer) {
+		t.Fatalf("Err() = %v, want %v", slow.Err(), ErrSlowConsumer)
+	}
+
+	// The events queued before the drop can still be read
+	if len(slow.Events()) != 2 {
+		t.Fatalf("%d events queued, want 2", len(slow.Events()))
+	}
+
+	select {
+	case <-fast.Done():
+		t.Fatalf("fast subscriber dropped: %v", fast.Err())
+	default:
+	}
+
+	// Unsubscribing a dropped subscriber keeps its error
+	hub.Unsubscribe(slow)
+
+	if !errors.Is(slow.Err(), ErrSlowConsumer) {
+		t.Fatalf("Err() after Unsubscribe() = %v, want %v", slow.Err(), ErrSlowConsumer)
+	}
+
+	hub.Publish(Event{Type: EventTip})
+
+	if len(slow.Events()) != 2 {
+		t.Fatalf("%d events queued after the drop, want 2", len(slow.Events()))
+	}
+}
+
+func TestHubClose(t *testing.T) {
+	ts := newTestServer(t, "")
+
+	hub := NewHub(ts.chain)
+
+	s, err := hub.Subscribe(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unsubscribed, err := hub.Subscribe(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hub.Unsubscribe(unsubscribed)
+
+	select {
+	case <-unsubscribed.Done():
+	default:
+		t.Fatal("unsubscribed subscriber not done")
+	}
+
+	if unsubscribed.Err() != nil {
+		t.Fatalf("Err() after Unsubscribe() = %v, want nil", unsubscribed.Err())
+	}
+
+	hub.Close()
+
+	select {
+	case <-s.Done():
+	default:
+		t.Fatal("subscriber not dropped on Close()")
+	}
+
+	if !errors.Is(s.Err(), ErrHubClosed) {
+		t.Fatalf("Err() = %v, want %v", s.Err(), ErrHubClosed)
+	}
+
+	if _, err := hub.Subscribe(Filter{}); !errors.Is(err, ErrHubClosed) {
+		t.Fatalf("Subscribe() after Close() error = %v, want %v", err, ErrHubClosed)
+	}
+}
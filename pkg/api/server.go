@@ -0,0 +1,376 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+	"github.com/herlon214/ipfs-blockchain/pkg/chain"
+	"github.com/herlon214/ipfs-blockchain/pkg/mempool"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+	"github.com/herlon214/ipfs-blockchain/pkg/wallets"
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+const (
+	// maxBodySize limits the size of the submitted transactions
+	maxBodySize = 1 << 20
+	// shutdownTimeout is how long the in flight requests are waited for
+	shutdownTimeout = 5 * time.Second
+)
+
+var ErrBadRequest = errors.New("bad request")
+
+// Submitter admits a transaction to the mempool and relays it to the peers.
+type Submitter interface {
+	SubmitTransaction(tx *transaction.Transaction) error
+}
+
+// PeerLister lists the peers the node exchanges blocks with.
+type PeerLister interface {
+	Peers() []peer.ID
+}
+
+// Server serves the HTTP JSON API of a node.
+type Server struct {
+	Chain     *chain.Chain
+	Pool      *mempool.Pool
+	Submitter Submitter
+	Peers     PeerLister
+	// Token, when set, is required as a bearer token on every request
+	Token string
+	// Events, when set, feeds the event stream
+	Events *Hub
+
+	mux *http.ServeMux
+}
+
+func New(c *chain.Chain, pool *mempool.Pool, submitter Submitter, peers PeerLister) *Server {
+	s := &Server{
+		Chain:     c,
+		Pool:      pool,
+		Submitter: submitter,
+		Peers:     peers,
+		mux:       http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/tip", s.get(s.handleTip))
+	s.mux.HandleFunc("/blocks/", s.get(s.handleBlock))
+	s.mux.HandleFunc("/txs", s.handleSubmit)
+	s.mux.HandleFunc("/txs/", s.get(s.handleTransaction))
+	s.mux.HandleFunc("/addresses/", s.get(s.handleAddress))
+	s.mux.HandleFunc("/mempool", s.get(s.handleMempool))
+	s.mux.HandleFunc("/peers", s.get(s.handlePeers))
+	s.mux.HandleFunc("/events", s.get(s.handleEvents))
+
+	return s
+}
+
+// ServeHTTP checks the bearer token and dispatches the request.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.Token != "" && !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="blockchain"`)
+		writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+
+		return
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+// Serve serves the API on the listener until ctx is done. The contexts of
+// the requests are derived from ctx, so long running requests end with it.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	srv := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("API shutdown: %s\n", err)
+		}
+	}()
+
+	err := srv.Serve(ln)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	const prefix = "Bearer "
+
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, prefix) {
+		return false
+	}
+
+	token := header[len(prefix):]
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1
+}
+
+// get restricts a handler to GET requests.
+func (s *Server) get(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+
+			return
+		}
+
+		h(w, r)
+	}
+}
+
+// GET /tip
+func (s *Server) handleTip(w http.ResponseWriter, r *http.Request) {
+	tip, err := s.Chain.Tip()
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewTip(tip))
+}
+
+// GET /blocks/{hash or height}
+func (s *Server) handleBlock(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/blocks/")
+
+	var b *block.Block
+	var err error
+
+	if height, parseErr := strconv.ParseUint(id, 10, 64); parseErr == nil {
+		b, err = s.Chain.BlockAtHeight(height)
+	} else {
+		var hash []byte
+
+		hash, err = decodeHex("block hash", id)
+		if err == nil {
+			b, err = s.Chain.GetBlock(hash)
+		}
+	}
+
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewBlock(b))
+}
+
+// GET /txs/{id}
+func (s *Server) handleTransaction(w http.ResponseWriter, r *http.Request) {
+	id, err := decodeHex("transaction ID", strings.TrimPrefix(r.URL.Path, "/txs/"))
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	if tx, ok := s.Pool.Get(id); ok {
+		writeJSON(w, http.StatusOK, TransactionStatus{Transaction: NewTransaction(tx)})
+		return
+	}
+
+	b, err := s.Chain.FindTransactionBlock(id)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	for _, tx := range b.Transactions {
+		if bytes.Equal(tx.ID, id) {
+			writeJSON(w, http.StatusOK, TransactionStatus{
+				Transaction: NewTransaction(tx),
+				Confirmed:   true,
+				BlockHash:   hex.EncodeToString(b.Hash),
+				Height:      b.Height,
+			})
+
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, chain.ErrTransactionNotFound)
+}
+
+// GET /addresses/{address}/balance and /addresses/{address}/utxos
+func (s *Server) handleAddress(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/addresses/"), "/")
+	if len(parts) != 2 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown path %s", r.URL.Path))
+		return
+	}
+
+	address := parts[0]
+
+	pubKeyHash, err := wallets.DecodeAddress([]byte(address))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("%w: address %s: %s", ErrBadRequest, address, err))
+		return
+	}
+
+	switch parts[1] {
+	case "balance":
+		balance, err := s.Chain.Balance(pubKeyHash)
+		if err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, Balance{Address: address, Balance: balance})
+	case "utxos":
+		utxos, err := s.Chain.ListUTXO(pubKeyHash)
+		if err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+
+		result := make([]UTXO, 0, len(utxos))
+		for _, utxo := range utxos {
+			result = append(result, UTXO{
+				TxID:  hex.EncodeToString(utxo.TxID),
+				Index: utxo.Index,
+				Value: utxo.Output.Value,
+			})
+		}
+
+		writeJSON(w, http.StatusOK, result)
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown path %s", r.URL.Path))
+	}
+}
+
+// GET /mempool
+func (s *Server) handleMempool(w http.ResponseWriter, r *http.Request) {
+	txs := s.Pool.Transactions()
+
+	result := Mempool{
+		Count:        len(txs),
+		Size:         s.Pool.Size(),
+		Transactions: make([]MempoolEntry, 0, len(txs)),
+	}
+
+	for _, tx := range txs {
+		fee, _ := s.Pool.Fee(tx.ID)
+
+		result.Transactions = append(result.Transactions, MempoolEntry{
+			Transaction: NewTransaction(tx),
+			Fee:         fee,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// GET /peers
+func (s *Server) handlePeers(w http.ResponseWriter, r *http.Request) {
+	peers := s.Peers.Peers()
+
+	result := make([]string, 0, len(peers))
+	for _, id := range peers {
+		result = append(result, id.Pretty())
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// POST /txs
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+
+		return
+	}
+
+	var req SubmitRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("%w: %s", ErrBadRequest, err))
+		return
+	}
+
+	data, err := decodeHex("transaction", req.Tx)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	tx, err := transaction.Deserialize(data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("%w: transaction: %s", ErrBadRequest, err))
+		return
+	}
+
+	if err := s.Submitter.SubmitTransaction(tx); err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, SubmitResponse{ID: hex.EncodeToString(tx.ID)})
+}
+
+func decodeHex(name string, value string) ([]byte, error) {
+	data, err := hex.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, fmt.Errorf("%w: invalid %s %q", ErrBadRequest, name, value)
+	}
+
+	return data, nil
+}
+
+// statusOf maps the errors of the chain and the mempool to HTTP statuses.
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, ErrBadRequest),
+		errors.Is(err, mempool.ErrInvalidTransaction):
+		return http.StatusBadRequest
+	case errors.Is(err, chain.ErrBlockNotFound),
+		errors.Is(err, chain.ErrTransactionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, mempool.ErrAlreadyExists),
+		errors.Is(err, mempool.ErrDoubleSpend):
+		return http.StatusConflict
+	case errors.Is(err, mempool.ErrPoolFull):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("API response: %s\n", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, Error{Error: err.Error()})
+}
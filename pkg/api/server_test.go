@@ -0,0 +1,386 @@
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/chain"
+	"github.com/herlon214/ipfs-blockchain/pkg/config"
+	"github.com/herlon214/ipfs-blockchain/pkg/mempool"
+	"github.com/herlon214/ipfs-blockchain/pkg/params"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+	"github.com/herlon214/ipfs-blockchain/pkg/wallets"
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// poolSubmitter admits the transactions to the pool without relaying them.
+type poolSubmitter struct {
+	pool *mempool.Pool
+	// err, when set, is returned instead of admitting the transaction
+	err error
+}
+
+func (s *poolSubmitter) SubmitTransaction(tx *transaction.Transaction) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	return s.pool.Add(tx)
+}
+
+type noPeers struct{}
+
+func (noPeers) Peers() []peer.ID { return nil }
+
+type testServer struct {
+	*httptest.Server
+
+	chain     *chain.Chain
+	pool      *mempool.Pool
+	submitter *poolSubmitter
+	wallet    *wallets.Wallet
+}
+
+func newTestServer(t *testing.T, token string) *testServer {
+	t.Helper()
+
+	w, err := wallets.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	cfg.DataDir = t.TempDir()
+	cfg.Network = params.Test.Name
+	cfg.Genesis.Address = string(w.Address())
+
+	c, err := chain.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := mempool.New(c, 0)
+	submitter := &poolSubmitter{pool: pool}
+
+	s := New(c, pool, submitter, noPeers{})
+	s.Token = token
+
+	ts := &testServer{
+		Server:    httptest.NewServer(s),
+		chain:     c,
+		pool:      pool,
+		submitter: submitter,
+		wallet:    w,
+	}
+
+	t.Cleanup(func() {
+		ts.Close()
+		c.Database.Close()
+	})
+
+	return ts
+}
+
+// do sends the request and decodes the JSON response into result, when not
+// nil, returning the status code.
+func (ts *testServer) do(t *testing.T, method string, path string, header http.Header, body []byte, result interface{}) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, ts.URL+path, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			t.Fatalf("%s %s: %s", method, path, err)
+		}
+	}
+
+	return resp.StatusCode
+}
+
+func (ts *testServer) payment(t *testing.T, amount int) *transaction.Transaction {
+	t.Helper()
+
+	to, err := wallets.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := ts.chain.NewTransaction(ts.wallet, string(to.Address()), amount, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tx
+}
+
+func submitBody(t *testing.T, tx *transaction.Transaction) []byte {
+	t.Helper()
+
+	data, err := tx.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := json.Marshal(SubmitRequest{Tx: hex.EncodeToString(data)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return body
+}
+
+func TestToken(t *testing.T) {
+	ts := newTestServer(t, "secret")
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"missing", "", http.StatusUnauthorized},
+		{"wrong", "Bearer guess", http.StatusUnauthorized},
+		{"without scheme", "secret", http.StatusUnauthorized},
+		{"prefix of the token", "Bearer secre", http.StatusUnauthorized},
+		{"valid", "Bearer secret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.header != "" {
+				header.Set("Authorization", tt.header)
+			}
+
+			var result json.RawMessage
+			if status := ts.do(t, http.MethodGet, "/tip", header, nil, &result); status != tt.want {
+				t.Fatalf("status = %d, want %d: %s", status, tt.want, result)
+			}
+		})
+	}
+
+	// The client sends its token
+	if _, err := NewClient(ts.URL, "secret").Mempool(); err != nil {
+		t.Fatal(err)
+	}
+
+	var statusErr *StatusError
+	if _, err := NewClient(ts.URL, "").Mempool(); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Mempool() without token error = %v, want status %d", err, http.StatusUnauthorized)
+	}
+}
+
+func TestSubmitBodyLimit(t *testing.T) {
+	ts := newTestServer(t, "")
+
+	tx := submitBody(t, ts.payment(t, 10))
+
+	// A valid request padded over the limit with whitespace, which JSON
+	// allows anywhere between tokens
+	body := append(bytes.Repeat([]byte(" "), maxBodySize), tx...)
+
+	var apiErr Error
+	if status := ts.do(t, http.MethodPost, "/txs", nil, body, &apiErr); status != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d: %s", status, http.StatusBadRequest, apiErr.Error)
+	}
+
+	if ts.pool.Count() != 0 {
+		t.Fatal("transaction over the body limit was admitted")
+	}
+
+	// The same request under the limit goes through
+	body = append(bytes.Repeat([]byte(" "), maxBodySize-len(tx)), tx...)
+
+	if status := ts.do(t, http.MethodPost, "/txs", nil, body, &SubmitResponse{}); status != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d", status, http.StatusAccepted)
+	}
+}
+
+func TestSubmitStatuses(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"accepted", nil, http.StatusAccepted},
+		{"invalid", fmt.Errorf("%w: bad signature", mempool.ErrInvalidTransaction), http.StatusBadRequest},
+		{"already in the pool", mempool.ErrAlreadyExists, http.StatusConflict},
+		{"double spend", mempool.ErrDoubleSpend, http.StatusConflict},
+		{"pool full", mempool.ErrPoolFull, http.StatusServiceUnavailable},
+		{"unexpected", errors.New("disk on fire"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, "")
+			ts.submitter.err = tt.err
+
+			var result json.RawMessage
+			if status := ts.do(t, http.MethodPost, "/txs", nil, submitBody(t, ts.payment(t, 10)), &result); status != tt.want {
+				t.Fatalf("status = %d, want %d: %s", status, tt.want, result)
+			}
+		})
+	}
+}
+
+func TestSubmitRequests(t *testing.T) {
+	ts := newTestServer(t, "")
+	tx := ts.payment(t, 10)
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		want   int
+	}{
+		{"wrong method", http.MethodGet, "", http.StatusMethodNotAllowed},
+		{"not JSON", http.MethodPost, "tx", http.StatusBadRequest},
+		{"not hex", http.MethodPost, `{"tx": "zz"}`, http.StatusBadRequest},
+		{"empty", http.MethodPost, `{"tx": ""}`, http.StatusBadRequest},
+		{"not a transaction", http.MethodPost, `{"tx": "00ff"}`, http.StatusBadRequest},
+		{"valid", http.MethodPost, string(submitBody(t, tx)), http.StatusAccepted},
+		{"resubmitted", http.MethodPost, string(submitBody(t, tx)), http.StatusConflict},
+		{"double spend", http.MethodPost, string(submitBody(t, ts.payment(t, 20))), http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result json.RawMessage
+			if status := ts.do(t, tt.method, "/txs", nil, []byte(tt.body), &result); status != tt.want {
+				t.Fatalf("status = %d, want %d: %s", status, tt.want, result)
+			}
+		})
+	}
+}
+
+func TestLookups(t *testing.T) {
+	ts := newTestServer(t, "")
+
+	tip, err := ts.chain.Tip()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	genesis, err := ts.chain.GetBlock(tip.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pending := ts.payment(t, 10)
+	if err := ts.pool.Add(pending); err != nil {
+		t.Fatal(err)
+	}
+
+	genesisHash := hex.EncodeToString(genesis.Hash)
+	coinbaseID := hex.EncodeToString(genesis.Transactions[0].ID)
+	pendingID := hex.EncodeToString(pending.ID)
+	unknown := strings.Repeat("ab", 32)
+
+	t.Run("blocks", func(t *testing.T) {
+		tests := []struct {
+			path string
+			want int
+		}{
+			{"/blocks/0", http.StatusOK},
+			{"/blocks/" + genesisHash, http.StatusOK},
+			{"/blocks/1", http.StatusNotFound},
+			{"/blocks/" + unknown, http.StatusNotFound},
+			{"/blocks/not-hex", http.StatusBadRequest},
+		}
+
+		for _, tt := range tests {
+			var b Block
+			if status := ts.do(t, http.MethodGet, tt.path, nil, nil, &b); status != tt.want {
+				t.Fatalf("GET %s status = %d, want %d", tt.path, status, tt.want)
+			}
+
+			if tt.want == http.StatusOK && (b.Hash != genesisHash || len(b.Transactions) != 1 || b.Transactions[0].ID != coinbaseID) {
+				t.Fatalf("GET %s = %+v", tt.path, b)
+			}
+		}
+	})
+
+	t.Run("transactions", func(t *testing.T) {
+		tests := []struct {
+			path      string
+			want      int
+			id        string
+			confirmed bool
+		}{
+			{"/txs/" + coinbaseID, http.StatusOK, coinbaseID, true},
+			{"/txs/" + pendingID, http.StatusOK, pendingID, false},
+			{"/txs/" + unknown, http.StatusNotFound, "", false},
+			{"/txs/not-hex", http.StatusBadRequest, "", false},
+		}
+
+		for _, tt := range tests {
+			var status TransactionStatus
+			if code := ts.do(t, http.MethodGet, tt.path, nil, nil, &status); code != tt.want {
+				t.Fatalf("GET %s status = %d, want %d", tt.path, code, tt.want)
+			}
+
+			if tt.want != http.StatusOK {
+				continue
+			}
+
+			if status.ID != tt.id || status.Confirmed != tt.confirmed {
+				t.Fatalf("GET %s = %+v", tt.path, status)
+			}
+
+			if tt.confirmed && (status.BlockHash != genesisHash || status.Height != 0) {
+				t.Fatalf("GET %s block = %s at %d, want %s at 0", tt.path, status.BlockHash, status.Height, genesisHash)
+			}
+		}
+	})
+
+	t.Run("addresses", func(t *testing.T) {
+		var balance Balance
+		if status := ts.do(t, http.MethodGet, "/addresses/"+string(ts.wallet.Address())+"/balance", nil, nil, &balance); status != http.StatusOK || balance.Balance != transaction.Reward {
+			t.Fatalf("balance status = %d, balance = %+v", status, balance)
+		}
+
+		utxos, err := NewClient(ts.URL, "").UTXOs(string(ts.wallet.Address()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(utxos) != 1 || hex.EncodeToString(utxos[0].TxID) != coinbaseID || utxos[0].Output.Value != transaction.Reward {
+			t.Fatalf("UTXOs() = %+v", utxos)
+		}
+
+		var apiErr Error
+		if status := ts.do(t, http.MethodGet, "/addresses/invalid/balance", nil, nil, &apiErr); status != http.StatusBadRequest {
+			t.Fatalf("invalid address status = %d, want %d", status, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("mempool", func(t *testing.T) {
+		pool, err := NewClient(ts.URL, "").Mempool()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if pool.Count != 1 || pool.Transactions[0].ID != pendingID || pool.Transactions[0].Fee != 1 {
+			t.Fatalf("Mempool() = %+v", pool)
+		}
+	})
+}
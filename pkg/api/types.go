@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/hex"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+	"github.com/herlon214/ipfs-blockchain/pkg/chain"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+	"github.com/herlon214/ipfs-blockchain/pkg/wallets"
+)
+
+// Hashes, IDs, keys and signatures are hex encoded in every response.
+
+type Tip struct {
+	Hash   string `json:"hash"`
+	Height uint64 `json:"height"`
+	// Work is the cumulative work of the chain as a decimal string
+	Work string `json:"work"`
+}
+
+type Block struct {
+	Hash         string        `json:"hash"`
+	Version      int32         `json:"version"`
+	Height       uint64        `json:"height"`
+	Timestamp    int64         `json:"timestamp"`
+	Bits         uint32        `json:"bits"`
+	PrevHash     string        `json:"prevHash"`
+	MerkleRoot   string        `json:"merkleRoot"`
+	Nonce        uint64        `json:"nonce"`
+	Transactions []Transaction `json:"transactions"`
+}
+
+type Transaction struct {
+	ID       string   `json:"id"`
+	Coinbase bool     `json:"coinbase"`
+	Inputs   []Input  `json:"inputs"`
+	Outputs  []Output `json:"outputs"`
+}
+
+type Input struct {
+	TxID      string `json:"txId"`
+	Out       int    `json:"out"`
+	Signature string `json:"signature"`
+	PubKey    string `json:"pubKey"`
+}
+
+type Output struct {
+	Value   int    `json:"value"`
+	Address string `json:"address"`
+}
+
+// TransactionStatus is a transaction along with where it was found.
+type TransactionStatus struct {
+	Transaction
+	Confirmed bool `json:"confirmed"`
+	// BlockHash and Height are only set for confirmed transactions
+	BlockHash string `json:"blockHash,omitempty"`
+	Height    uint64 `json:"height,omitempty"`
+}
+
+type Balance struct {
+	Address string `json:"address"`
+	Balance int    `json:"balance"`
+}
+
+type UTXO struct {
+	TxID  string `json:"txId"`
+	Index int    `json:"index"`
+	Value int    `json:"value"`
+}
+
+type MempoolEntry struct {
+	Transaction
+	Fee int `json:"fee"`
+}
+
+type Mempool struct {
+	Count        int            `json:"count"`
+	Size         int            `json:"size"`
+	Transactions []MempoolEntry `json:"transactions"`
+}
+
+// SubmitRequest carries a signed transaction, serialized with
+// transaction.Serialize and hex encoded.
+type SubmitRequest struct {
+	Tx string `json:"tx"`
+}
+
+type SubmitResponse struct {
+	ID string `json:"id"`
+}
+
+type Error struct {
+	Error string `json:"error"`
+}
+
+func NewTip(t chain.Tip) Tip {
+	return Tip{
+		Hash:   hex.EncodeToString(t.Hash),
+		Height: t.Height,
+		Work:   t.Work.String(),
+	}
+}
+
+func NewBlock(b *block.Block) Block {
+	txs := make([]Transaction, 0, len(b.Transactions))
+	for _, tx := range b.Transactions {
+		txs = append(txs, NewTransaction(tx))
+	}
+
+	return Block{
+		Hash:         hex.EncodeToString(b.Hash),
+		Version:      b.Version,
+		Height:       b.Height,
+		Timestamp:    b.Timestamp,
+		Bits:         b.Bits,
+		PrevHash:     hex.EncodeToString(b.PrevHash),
+		MerkleRoot:   hex.EncodeToString(b.MerkleRoot),
+		Nonce:        b.Nonce,
+		Transactions: txs,
+	}
+}
+
+func NewTransaction(tx *transaction.Transaction) Transaction {
+	inputs := make([]Input, 0, len(tx.Inputs))
+	for _, in := range tx.Inputs {
+		inputs = append(inputs, Input{
+			TxID:      hex.EncodeToString(in.ID),
+			Out:       in.Out,
+			Signature: hex.EncodeToString(in.Signature),
+			PubKey:    hex.EncodeToString(in.PubKey),
+		})
+	}
+
+	outputs := make([]Output, 0, len(tx.Outputs))
+	for _, out := range tx.Outputs {
+		outputs = append(outputs, Output{
+			Value:   out.Value,
+			Address: string(wallets.EncodeAddress(out.PubKeyHash)),
+		})
+	}
+
+	return Transaction{
+		ID:       hex.EncodeToString(tx.ID),
+		Coinbase: tx.IsCoinBase(),
+		Inputs:   inputs,
+		Outputs:  outputs,
+	}
+}
@@ -2,75 +2,134 @@ package block
 
 import (
 	"bytes"
-	"crypto/sha256"
+	"context"
 	"encoding/gob"
+	"errors"
+	"fmt"
+	"runtime"
+	"time"
 
+	"github.com/herlon214/ipfs-blockchain/pkg/merkle"
 	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
 )
 
+const Version = 1
+
+// ErrInvalidBlock is returned, possibly wrapped, for blocks that cannot be
+// decoded or do not follow the consensus rules.
+var ErrInvalidBlock = errors.New("invalid block")
+
+type Header struct {
+	Version    int32
+	Height     uint64
+	Timestamp  int64
+	Bits       uint32
+	PrevHash   []byte
+	MerkleRoot []byte
+	Nonce      uint64
+}
+
 type Block struct {
-	Hash     []byte
-	PrevHash []byte
-	Nonce    int
+	Header
 
+	Hash         []byte
 	Transactions []*transaction.Transaction
 }
 
-func New(txs []*transaction.Transaction, prevHash []byte) *Block {
+// New creates a block that still has to be mined with DeriveHash or a
+// ProofOfWork.
+func New(txs []*transaction.Transaction, prevHash []byte, height uint64, bits uint32) *Block {
 	b := Block{
-		PrevHash:     prevHash,
-		Nonce:        0,
+		Header: Header{
+			Version:   Version,
+			Height:    height,
+			Timestamp: time.Now().Unix(),
+			Bits:      bits,
+			PrevHash:  prevHash,
+			Nonce:     0,
+		},
 		Transactions: txs,
 	}
 
-	b.DeriveHash()
+	b.MerkleRoot = b.HashTransactions()
 
 	return &b
 }
 
-func (b *Block) DeriveHash() {
+// Bytes encodes the header with fixed width integers, which is the payload
+// hashed by the proof of work.
+func (h *Header) Bytes() []byte {
+	return bytes.Join(
+		[][]byte{
+			ToHex(int64(h.Version)),
+			ToHex(int64(h.Height)),
+			ToHex(h.Timestamp),
+			ToHex(int64(h.Bits)),
+			h.PrevHash,
+			h.MerkleRoot,
+			ToHex(int64(h.Nonce)),
+		},
+		[]byte{},
+	)
+}
+
+// DeriveHash mines the block using every available CPU.
+func (b *Block) DeriveHash() error {
 	pow := NewProof(b)
-	nonce, hash := pow.Run()
+	nonce, hash, err := pow.Run(context.Background(), runtime.NumCPU())
+	if err != nil {
+		return err
+	}
 
 	b.Hash = hash
 	b.Nonce = nonce
+
+	return nil
 }
 
-func (b *Block) Serialize() []byte {
+func (b *Block) Serialize() ([]byte, error) {
 	var res bytes.Buffer
 
 	encoder := gob.NewEncoder(&res)
 
 	err := encoder.Encode(b)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	return res.Bytes()
+	return res.Bytes(), nil
 }
 
+// HashTransactions returns the merkle root of the block transactions IDs.
 func (b *Block) HashTransactions() []byte {
-	var txHashes [][]byte
-	var txHash [32]byte
+	return b.merkleTree().Root()
+}
+
+// TransactionProof returns the merkle proof that the transaction with the
+// given ID is part of the block.
+func (b *Block) TransactionProof(txID []byte) (merkle.Proof, error) {
+	return b.merkleTree().Proof(txID)
+}
+
+func (b *Block) merkleTree() *merkle.Tree {
+	var txIDs [][]byte
 
 	for _, tx := range b.Transactions {
-		txHashes = append(txHashes, tx.ID)
+		txIDs = append(txIDs, tx.ID)
 	}
 
-	txHash = sha256.Sum256(bytes.Join(txHashes, []byte{}))
-
-	return txHash[:]
+	return merkle.New(txIDs)
 }
 
-func Deserialize(data []byte) *Block {
+func Deserialize(data []byte) (*Block, error) {
 	var block Block
 
 	decoder := gob.NewDecoder(bytes.NewReader(data))
 
 	err := decoder.Decode(&block)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("%w: %s", ErrInvalidBlock, err)
 	}
 
-	return &block
+	return &block, nil
 }
@@ -2,58 +2,133 @@ package block
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/binary"
+	"errors"
 	"math"
 	"math/big"
+	"sync"
+	"sync/atomic"
 )
 
-const Difficulty = 15
+// ctxCheckInterval is the number of hashes a worker computes between checks
+// for cancellation
+const ctxCheckInterval = 1 << 12
+
+var ErrNonceExhausted = errors.New("nonce space exhausted")
 
 type ProofOfWork struct {
 	Block  *Block
 	Target *big.Int
+
+	// hashes counts the hashes computed so far by Run
+	hashes uint64
 }
 
 func NewProof(b *Block) *ProofOfWork {
 	target := big.NewInt(1)
-	target.Lsh(target, uint(256-Difficulty))
+	target.Lsh(target, uint(256-b.Bits))
+
+	return &ProofOfWork{Block: b, Target: target}
+}
+
+func (p *ProofOfWork) InitData(nonce uint64) []byte {
+	header := p.Block.Header
+	header.Nonce = nonce
 
-	return &ProofOfWork{b, target}
+	return header.Bytes()
 }
 
-func (p *ProofOfWork) InitData(nonce int) []byte {
-	return bytes.Join(
-		[][]byte{
-			p.Block.PrevHash,
-			p.Block.HashTransactions(),
-			ToHex(int64(nonce)),
-			ToHex(int64(Difficulty)),
-		},
-		[]byte{},
-	)
+// Hashes returns how many hashes Run computed so far. It is safe to call
+// while Run is in progress, which allows measuring the hash rate.
+func (p *ProofOfWork) Hashes() uint64 {
+	return atomic.LoadUint64(&p.hashes)
 }
 
-func (p *ProofOfWork) Run() (int, []byte) {
-	var intHash big.Int
-	var hash [32]byte
+// Run looks for a nonce whose header hash is below the target, splitting the
+// nonce space across the given number of workers. It stops as soon as a
+// nonce is found or ctx is done.
+func (p *ProofOfWork) Run(ctx context.Context, workers int) (uint64, []byte, error) {
+	if workers < 1 {
+		workers = 1
+	}
 
-	nonce := 0
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	for nonce < math.MaxInt64 {
-		data := p.InitData(nonce)
-		hash = sha256.Sum256(data)
+	type result struct {
+		nonce uint64
+		hash  []byte
+	}
 
-		intHash.SetBytes(hash[:])
+	found := make(chan result, workers)
+	done := make(chan struct{})
 
-		if intHash.Cmp(p.Target) == -1 {
-			break
-		} else {
-			nonce++
-		}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func(start uint64, step uint64) {
+			defer wg.Done()
+
+			var intHash big.Int
+			var count uint64
+
+			header := p.Block.Header
+
+			for nonce := start; ; nonce += step {
+				count++
+				if count%ctxCheckInterval == 0 {
+					atomic.AddUint64(&p.hashes, ctxCheckInterval)
+
+					if ctx.Err() != nil {
+						return
+					}
+				}
+
+				header.Nonce = nonce
+				hash := sha256.Sum256(header.Bytes())
+				intHash.SetBytes(hash[:])
+
+				if intHash.Cmp(p.Target) == -1 {
+					atomic.AddUint64(&p.hashes, count%ctxCheckInterval)
+					found <- result{nonce, hash[:]}
+
+					return
+				}
+
+				if math.MaxUint64-nonce < step {
+					return
+				}
+			}
+		}(uint64(i), uint64(workers))
 	}
 
-	return nonce, hash[:]
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case res := <-found:
+		cancel()
+		<-done
+
+		return res.nonce, res.hash, nil
+	case <-done:
+		select {
+		case res := <-found:
+			return res.nonce, res.hash, nil
+		default:
+		}
+
+		if err := ctx.Err(); err != nil {
+			return 0, nil, err
+		}
+
+		return 0, nil, ErrNonceExhausted
+	}
 }
 
 func (p *ProofOfWork) Validate() bool {
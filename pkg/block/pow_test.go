@@ -0,0 +1,114 @@
+package block
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/params"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+	"github.com/herlon214/ipfs-blockchain/pkg/wallets"
+)
+
+func newTestBlock(t *testing.T, bits uint32) *Block {
+	t.Helper()
+
+	w, err := wallets.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	coinbase, err := transaction.CoinBase(string(w.Address()), "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return New([]*transaction.Transaction{coinbase}, []byte("parent"), 1, bits)
+}
+
+func TestProofOfWorkRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		bits    uint32
+		workers int
+	}{
+		{"test network minimum, one worker", params.Test.MinBits, 1},
+		{"test network minimum, several workers", params.Test.MinBits, 4},
+		{"main network minimum, several workers", params.Main.MinBits, 8},
+		{"test network maximum, several workers", params.Test.MaxBits, 3},
+		{"no workers uses one", params.Test.MinBits, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBlock(t, tt.bits)
+			pow := NewProof(b)
+
+			nonce, hash, err := pow.Run(context.Background(), tt.workers)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if pow.Hashes() == 0 {
+				t.Error("Hashes() = 0 after a successful run")
+			}
+
+			expected := sha256.Sum256(pow.InitData(nonce))
+			if string(expected[:]) != string(hash) {
+				t.Fatalf("hash %x does not match the header hash %x", hash, expected)
+			}
+
+			target := new(big.Int).Lsh(big.NewInt(1), uint(256-tt.bits))
+			if new(big.Int).SetBytes(hash).Cmp(target) >= 0 {
+				t.Fatalf("hash %x is not below the target of %d bits", hash, tt.bits)
+			}
+
+			b.Nonce = nonce
+			if !pow.Validate() {
+				t.Fatal("Validate() = false for the nonce found")
+			}
+		})
+	}
+}
+
+func TestProofOfWorkRunCancel(t *testing.T) {
+	// No hash is below a target of 2^1
+	pow := NewProof(newTestBlock(t, 255))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, _, err := pow.Run(ctx, 4)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestDeriveHash(t *testing.T) {
+	b := newTestBlock(t, params.Test.MinBits)
+
+	if err := b.DeriveHash(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !NewProof(b).Validate() {
+		t.Fatal("Validate() = false after DeriveHash")
+	}
+
+	data, err := b.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := Deserialize(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(decoded.Hash) != string(b.Hash) || decoded.Nonce != b.Nonce || !NewProof(decoded).Validate() {
+		t.Fatal("decoded block does not keep its proof of work")
+	}
+}
@@ -2,30 +2,79 @@ package chain
 
 import (
 	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/dgraph-io/badger"
 	"github.com/herlon214/ipfs-blockchain/pkg/block"
+	"github.com/herlon214/ipfs-blockchain/pkg/config"
+	"github.com/herlon214/ipfs-blockchain/pkg/params"
 	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
 )
 
-const (
-	dbPath = "./blocks"
+var (
+	blockPrefix = []byte("block-")
+	utxoPrefix  = []byte("utxo-")
 )
 
+var (
+	ErrBlockNotFound       = errors.New("block not found")
+	ErrInvalidBlock        = block.ErrInvalidBlock
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrInvalidTransaction  = errors.New("invalid transaction")
+	ErrInsufficientFunds   = errors.New("insufficient funds")
+	ErrCorruptData         = errors.New("corrupt chain data")
+)
+
+// Exists reports whether the chain database was already created.
+func Exists(cfg *config.Config) bool {
+	if _, err := os.Stat(filepath.Join(cfg.ChainDir(), "MANIFEST")); os.IsNotExist(err) {
+		return false
+	}
+
+	return true
+}
+
 type Chain struct {
-	LastHash []byte
+	Params *params.Params
 
 	Database *badger.DB
+
+	mx sync.Mutex
+	// lastHash is the hash of the best chain tip, guarded by mx
+	lastHash   []byte
+	tipChanged chan struct{}
+	// connectMx serializes the blocks being connected
+	connectMx sync.Mutex
+
+	subscribers    map[int]func(Event)
+	nextSubscriber int
 }
 
-func New() *Chain {
+// New opens the chain database in the configured data directory, creating
+// the configured genesis block when the database is empty.
+func New(cfg *config.Config) (*Chain, error) {
 	var lastHash []byte
 
-	opts := badger.DefaultOptions(dbPath)
+	p := cfg.Params()
+
+	err := os.MkdirAll(cfg.ChainDir(), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := badger.DefaultOptions(cfg.ChainDir())
 	db, err := badger.Open(opts)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	err = db.Update(func(txn *badger.Txn) error {
@@ -33,17 +82,28 @@ func New() *Chain {
 		if err != nil {
 			if err == badger.ErrKeyNotFound {
 
-				coinbaseTx := transaction.CoinBase("abb9af250a0b4c505838b512ff7dc91a944d1d7dcb1c4b5936a02b56894b2e08", "")
-				genesis := block.New([]*transaction.Transaction{coinbaseTx}, []byte{})
-				genesis.DeriveHash()
+				genesis, err := newGenesis(cfg.GenesisParams(), p)
+				if err != nil {
+					return err
+				}
+
+				err = storeBlock(txn, genesis, BlockWork(genesis.Bits))
+				if err != nil {
+					return err
+				}
+
+				view := &txnView{txn: txn}
+				err = verifyTransactions(genesis, view)
+				if err != nil {
+					return err
+				}
 
-				key := bytes.Join([][]byte{[]byte("block-"), genesis.Hash}, []byte{})
-				err = txn.Set(key, genesis.Serialize())
+				err = setUndo(txn, genesis.Hash, view.undo)
 				if err != nil {
 					return err
 				}
 
-				err := txn.Set([]byte("lh"), genesis.Hash)
+				err = txn.Set([]byte("lh"), genesis.Hash)
 				if err != nil {
 					return err
 				}
@@ -57,99 +117,320 @@ func New() *Chain {
 		}
 
 		return itemResult.Value(func(val []byte) error {
-			lastHash = val
+			lastHash = append([]byte{}, val...)
 
 			return nil
 		})
 	})
 	if err != nil {
-		panic(err)
+		db.Close()
+
+		return nil, err
 	}
 
-	return &Chain{
-		Database: db,
-		LastHash: lastHash,
+	c := &Chain{
+		Database:    db,
+		lastHash:    lastHash,
+		Params:      p,
+		tipChanged:  make(chan struct{}),
+		subscribers: make(map[int]func(Event)),
 	}
+
+	err = c.indexWork()
+	if err != nil {
+		db.Close()
+
+		return nil, err
+	}
+
+	return c, nil
 }
 
-func (c *Chain) AddBlock(txs []*transaction.Transaction) {
-	var lastHash []byte
+// newGenesis mines the genesis block. A single worker is used so the same
+// settings always find the same nonce, giving every node the same genesis.
+func newGenesis(g params.Genesis, p *params.Params) (*block.Block, error) {
+	coinbaseTx, err := transaction.CoinBase(g.Address, g.Message, 0)
+	if err != nil {
+		return nil, fmt.Errorf("genesis address %q: %w", g.Address, err)
+	}
 
-	err := c.Database.View(func(txn *badger.Txn) error {
-		item, err := txn.Get([]byte("lh"))
+	genesis := block.New([]*transaction.Transaction{coinbaseTx}, []byte{}, 0, p.InitialBits)
+	genesis.Timestamp = g.Timestamp
+
+	nonce, hash, err := block.NewProof(genesis).Run(context.Background(), 1)
+	if err != nil {
+		return nil, err
+	}
+
+	genesis.Nonce = nonce
+	genesis.Hash = hash
+
+	return genesis, nil
+}
+
+// indexWork computes the cumulative work of the best chain for databases
+// created before it was stored.
+func (c *Chain) indexWork() error {
+	if _, err := c.Work(c.LastHash()); !errors.Is(err, ErrCorruptData) {
+		return err
+	}
+
+	var blocks []*block.Block
+
+	it := c.Iterator()
+	for {
+		b, err := it.Next()
 		if err != nil {
 			return err
 		}
 
-		return item.Value(func(val []byte) error {
-			lastHash = val
+		if b == nil {
+			break
+		}
 
-			return nil
-		})
+		blocks = append(blocks, b)
+	}
 
-	})
+	batch := c.Database.NewWriteBatch()
+	defer batch.Cancel()
+
+	work := new(big.Int)
+	for i := len(blocks) - 1; i >= 0; i-- {
+		work.Add(work, BlockWork(blocks[i].Bits))
+
+		if err := batch.Set(workKey(blocks[i].Hash), work.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	if err := batch.Set(tipKey(c.LastHash()), []byte{}); err != nil {
+		return err
+	}
+
+	return batch.Flush()
+}
+
+// AddBlock mines a new block with the given transactions on top of the
+// current last block and connects it to the chain.
+func (c *Chain) AddBlock(txs []*transaction.Transaction) (*block.Block, error) {
+	newBlock, err := c.NewBlockTemplate(txs)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+
+	if err := newBlock.DeriveHash(); err != nil {
+		return nil, err
 	}
 
-	newBlock := block.New(txs, lastHash)
-	newBlock.DeriveHash()
+	if err := c.ConnectBlock(newBlock); err != nil {
+		return nil, err
+	}
 
-	err = c.Database.Update(func(txn *badger.Txn) error {
-		key := bytes.Join([][]byte{[]byte("block-"), newBlock.Hash}, []byte{})
-		err := txn.Set(key, newBlock.Serialize())
+	return newBlock, nil
+}
+
+// NewBlockTemplate creates a block with the given transactions on top of the
+// current last block, ready to be mined.
+func (c *Chain) NewBlockTemplate(txs []*transaction.Transaction) (*block.Block, error) {
+	tip, err := c.GetBlock(c.LastHash())
+	if err != nil {
+		return nil, err
+	}
+
+	bits, err := c.NextBits(tip)
+	if err != nil {
+		return nil, err
+	}
+
+	return block.New(txs, tip.Hash, tip.Height+1, bits), nil
+}
+
+// LastHash returns the hash of the last block of the best chain.
+func (c *Chain) LastHash() []byte {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return c.lastHash
+}
+
+func (c *Chain) setLastHash(hash []byte) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.lastHash = hash
+}
+
+// TipChanged returns a channel closed the next time a block is connected.
+func (c *Chain) TipChanged() <-chan struct{} {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return c.tipChanged
+}
+
+func (c *Chain) notifyTipChanged() {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	close(c.tipChanged)
+	c.tipChanged = make(chan struct{})
+}
+
+// HasBlock reports whether the block with the given hash is stored.
+func (c *Chain) HasBlock(hash []byte) bool {
+	err := c.Database.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(blockKey(hash))
+
+		return err
+	})
+
+	return err == nil
+}
+
+// GetBlock loads the block with the given hash.
+func (c *Chain) GetBlock(hash []byte) (*block.Block, error) {
+	return getBlock(c.Database, hash)
+}
+
+// BlockAtHeight returns the block of the best chain at the given height.
+func (c *Chain) BlockAtHeight(height uint64) (*block.Block, error) {
+	it := c.Iterator()
+
+	for {
+		b, err := it.Next()
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		c.LastHash = newBlock.Hash
+		if b == nil || b.Height < height {
+			return nil, fmt.Errorf("%w: height %d", ErrBlockNotFound, height)
+		}
+
+		if b.Height == height {
+			return b, nil
+		}
+	}
+}
 
-		return txn.Set([]byte("lh"), newBlock.Hash)
+func getBlock(db *badger.DB, hash []byte) (*block.Block, error) {
+	var b *block.Block
 
+	err := db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(blockKey(hash))
+		if err != nil {
+			if err == badger.ErrKeyNotFound {
+				return fmt.Errorf("%w: %x", ErrBlockNotFound, hash)
+			}
+
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			b, err = block.Deserialize(val)
+			if err != nil {
+				return fmt.Errorf("%w: block %x: %s", ErrCorruptData, hash, err)
+			}
+
+			return nil
+		})
 	})
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// FindTransaction walks the chain looking for the transaction with the given ID.
+func (c *Chain) FindTransaction(ID []byte) (transaction.Transaction, error) {
+	b, err := c.FindTransactionBlock(ID)
+	if err != nil {
+		return transaction.Transaction{}, err
 	}
+
+	for _, tx := range b.Transactions {
+		if bytes.Equal(tx.ID, ID) {
+			return *tx, nil
+		}
+	}
+
+	return transaction.Transaction{}, ErrTransactionNotFound
 }
 
-func (c *Chain) PrintBlocks() {
-	nextHash := c.LastHash
+// FindTransactionBlock walks the chain looking for the block containing the
+// transaction with the given ID.
+func (c *Chain) FindTransactionBlock(ID []byte) (*block.Block, error) {
+	it := c.Iterator()
 
 	for {
-		if bytes.Equal(nextHash, []byte{}) {
+		b, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		if b == nil {
 			break
 		}
 
-		err := c.Database.View(func(txn *badger.Txn) error {
-			key := bytes.Join([][]byte{[]byte("block-"), nextHash}, []byte{})
-			item, err := txn.Get(key)
-			if err != nil {
-				return err
+		for _, tx := range b.Transactions {
+			if bytes.Equal(tx.ID, ID) {
+				return b, nil
 			}
+		}
+	}
 
-			err = item.Value(func(val []byte) error {
-				currentBlock := block.Deserialize(val)
+	return nil, ErrTransactionNotFound
+}
 
-				fmt.Println("------------------------------------------")
-				fmt.Printf("Previous hash: %x\n", currentBlock.PrevHash)
-				fmt.Printf("Transactions in block: %d\n", len(currentBlock.Transactions))
-				for _, tx := range currentBlock.Transactions {
-					fmt.Println(tx.String())
-				}
-				fmt.Printf("Block hash: %x\n", currentBlock.Hash)
-				fmt.Println("------------------------------------------")
+// SignTransaction signs the inputs of tx looking up the transactions they spend.
+func (c *Chain) SignTransaction(tx *transaction.Transaction, privKey *ecdsa.PrivateKey) error {
+	prevTxs, err := c.previousTransactions(tx)
+	if err != nil {
+		return err
+	}
 
-				nextHash = currentBlock.PrevHash
+	return tx.Sign(privKey, prevTxs)
+}
 
-				return nil
-			})
+func (c *Chain) previousTransactions(tx *transaction.Transaction) (map[string]transaction.Transaction, error) {
+	prevTxs := make(map[string]transaction.Transaction)
 
-			return err
-		})
+	for _, in := range tx.Inputs {
+		prevTx, err := c.FindTransaction(in.ID)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
 
+		prevTxs[hex.EncodeToString(prevTx.ID)] = prevTx
 	}
 
+	return prevTxs, nil
+}
+
+// PrintBlocks prints every block of the best chain, from the last one back
+// to the genesis block.
+func (c *Chain) PrintBlocks() error {
+	it := c.Iterator()
+
+	for {
+		currentBlock, err := it.Next()
+		if err != nil {
+			return err
+		}
+
+		if currentBlock == nil {
+			return nil
+		}
+
+		fmt.Println("------------------------------------------")
+		fmt.Printf("Height: %d\n", currentBlock.Height)
+		fmt.Printf("Timestamp: %s\n", time.Unix(currentBlock.Timestamp, 0).UTC())
+		fmt.Printf("Previous hash: %x\n", currentBlock.PrevHash)
+		fmt.Printf("Transactions in block: %d\n", len(currentBlock.Transactions))
+		for _, tx := range currentBlock.Transactions {
+			fmt.Println(tx.String())
+		}
+		fmt.Printf("Block hash: %x\n", currentBlock.Hash)
+		fmt.Println("------------------------------------------")
+	}
 }
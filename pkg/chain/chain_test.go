@@ -0,0 +1,391 @@
+package chain
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/config"
+	"github.com/herlon214/ipfs-blockchain/pkg/params"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+	"github.com/herlon214/ipfs-blockchain/pkg/wallets"
+)
+
+// newTestChain creates a chain on the test network whose genesis reward goes
+// to the returned wallet.
+func newTestChain(t *testing.T) (*Chain, *wallets.Wallet) {
+	t.Helper()
+
+	w, err := wallets.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	cfg.DataDir = t.TempDir()
+	cfg.Network = params.Test.Name
+	cfg.Genesis = config.Genesis{
+		Address:   string(w.Address()),
+		Timestamp: 1600000000,
+		Message:   "test genesis",
+	}
+
+	c, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		c.Database.Close()
+	})
+
+	return c, w
+}
+
+// genesisCoinbase returns the coinbase of the genesis block of c.
+func genesisCoinbase(t *testing.T, c *Chain) *transaction.Transaction {
+	t.Helper()
+
+	tip, err := c.Tip()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	genesis, err := c.GetBlock(tip.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return genesis.Transactions[0]
+}
+
+// spend builds a transaction signed by w spending the given outputs of
+// prevTx and paying the values to w.
+func spend(t *testing.T, w *wallets.Wallet, prevTx *transaction.Transaction, outs []int, values ...int) *transaction.Transaction {
+	t.Helper()
+
+	var inputs []transaction.Input
+	for _, out := range outs {
+		inputs = append(inputs, transaction.Input{ID: prevTx.ID, Out: out, PubKey: w.PublicKey})
+	}
+
+	var outputs []transaction.Output
+	for _, value := range values {
+		outputs = append(outputs, transaction.Output{Value: value, PubKeyHash: w.PublicKeyHash()})
+	}
+
+	tx, err := transaction.New(inputs, outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prevTxs := map[string]transaction.Transaction{hex.EncodeToString(prevTx.ID): *prevTx}
+	if err := tx.Sign(w.PrivateKey, prevTxs); err != nil {
+		t.Fatal(err)
+	}
+
+	return tx
+}
+
+func coinbase(t *testing.T, w *wallets.Wallet, values ...int) *transaction.Transaction {
+	t.Helper()
+
+	var outputs []transaction.Output
+	for _, value := range values {
+		outputs = append(outputs, transaction.Output{Value: value, PubKeyHash: w.PublicKeyHash()})
+	}
+
+	tx, err := transaction.New([]transaction.Input{{ID: []byte{}, Out: -1, PubKey: []byte("test coinbase")}}, outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tx
+}
+
+func TestAddBlockRejectsValueCreation(t *testing.T) {
+	tests := []struct {
+		name  string
+		txs   func(t *testing.T, w *wallets.Wallet, prev *transaction.Transaction) []*transaction.Transaction
+		match error
+	}{
+		{
+			name: "negative output",
+			txs: func(t *testing.T, w *wallets.Wallet, prev *transaction.Transaction) []*transaction.Transaction {
+				return []*transaction.Transaction{
+					coinbase(t, w, transaction.Reward),
+					spend(t, w, prev, []int{0}, -1000000, 1000100),
+				}
+			},
+			match: transaction.ErrInvalidValue,
+		},
+		{
+			name: "zero output",
+			txs: func(t *testing.T, w *wallets.Wallet, prev *transaction.Transaction) []*transaction.Transaction {
+				return []*transaction.Transaction{
+					coinbase(t, w, transaction.Reward),
+					spend(t, w, prev, []int{0}, 0, 100),
+				}
+			},
+			match: transaction.ErrInvalidValue,
+		},
+		{
+			name: "no inputs",
+			txs: func(t *testing.T, w *wallets.Wallet, prev *transaction.Transaction) []*transaction.Transaction {
+				return []*transaction.Transaction{
+					coinbase(t, w, transaction.Reward),
+					spend(t, w, prev, nil, 50),
+				}
+			},
+			match: transaction.ErrNoInputs,
+		},
+		{
+			name: "no outputs",
+			txs: func(t *testing.T, w *wallets.Wallet, prev *transaction.Transaction) []*transaction.Transaction {
+				return []*transaction.Transaction{
+					coinbase(t, w, transaction.Reward),
+					spend(t, w, prev, []int{0}),
+				}
+			},
+			match: transaction.ErrNoOutputs,
+		},
+		{
+			name: "output overflow",
+			txs: func(t *testing.T, w *wallets.Wallet, prev *transaction.Transaction) []*transaction.Transaction {
+				return []*transaction.Transaction{
+					coinbase(t, w, transaction.Reward),
+					spend(t, w, prev, []int{0}, math.MaxInt, math.MaxInt, 2),
+				}
+			},
+			match: transaction.ErrValueOverflow,
+		},
+		{
+			name: "spends more than its inputs",
+			txs: func(t *testing.T, w *wallets.Wallet, prev *transaction.Transaction) []*transaction.Transaction {
+				return []*transaction.Transaction{
+					coinbase(t, w, transaction.Reward),
+					spend(t, w, prev, []int{0}, 60, 41),
+				}
+			},
+			match: ErrInvalidTransaction,
+		},
+		{
+			name: "same output spent twice",
+			txs: func(t *testing.T, w *wallets.Wallet, prev *transaction.Transaction) []*transaction.Transaction {
+				return []*transaction.Transaction{
+					coinbase(t, w, transaction.Reward),
+					spend(t, w, prev, []int{0, 0}, 200),
+				}
+			},
+			match: ErrInvalidTransaction,
+		},
+		{
+			name: "negative coinbase output",
+			txs: func(t *testing.T, w *wallets.Wallet, prev *transaction.Transaction) []*transaction.Transaction {
+				return []*transaction.Transaction{
+					coinbase(t, w, transaction.Reward+1000, -1000),
+				}
+			},
+			match: transaction.ErrInvalidValue,
+		},
+		{
+			name: "coinbase overflow",
+			txs: func(t *testing.T, w *wallets.Wallet, prev *transaction.Transaction) []*transaction.Transaction {
+				return []*transaction.Transaction{
+					coinbase(t, w, math.MaxInt, math.MaxInt),
+				}
+			},
+			match: transaction.ErrValueOverflow,
+		},
+		{
+			name: "coinbase above the reward",
+			txs: func(t *testing.T, w *wallets.Wallet, prev *transaction.Transaction) []*transaction.Transaction {
+				return []*transaction.Transaction{
+					coinbase(t, w, transaction.Reward+1),
+				}
+			},
+			match: ErrInvalidCoinbase,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newTestChain(t)
+			before, err := c.Tip()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = c.AddBlock(tt.txs(t, w, genesisCoinbase(t, c)))
+			if !errors.Is(err, ErrInvalidBlock) || !errors.Is(err, tt.match) {
+				t.Fatalf("AddBlock() error = %v, want %v and %v", err, ErrInvalidBlock, tt.match)
+			}
+
+			after, err := c.Tip()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if after.Height != before.Height {
+				t.Fatalf("tip height = %d, want %d", after.Height, before.Height)
+			}
+
+			balance, err := c.Balance(w.PublicKeyHash())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if balance != transaction.Reward {
+				t.Fatalf("balance = %d, want %d", balance, transaction.Reward)
+			}
+
+			if err := c.Verify(context.Background()); err != nil {
+				t.Fatalf("Verify() = %v", err)
+			}
+		})
+	}
+}
+
+func TestAddBlockAcceptsPayment(t *testing.T) {
+	c, w := newTestChain(t)
+
+	to, err := wallets.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := c.NewTransaction(w, string(to.Address()), 60, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.AddBlock([]*transaction.Transaction{coinbase(t, w, transaction.Reward+5), tx}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		wallet *wallets.Wallet
+		want   int
+	}{
+		{w, transaction.Reward - 60 - 5 + transaction.Reward + 5},
+		{to, 60},
+	} {
+		balance, err := c.Balance(tt.wallet.PublicKeyHash())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if balance != tt.want {
+			t.Errorf("balance of %s = %d, want %d", tt.wallet.Address(), balance, tt.want)
+		}
+	}
+
+	if err := c.Verify(context.Background()); err != nil {
+		t.Fatalf("Verify() = %v", err)
+	}
+}
+
+func TestVerifyTransactionsRejectsValueCreation(t *testing.T) {
+	c, w := newTestChain(t)
+	prev := genesisCoinbase(t, c)
+
+	// Verify replays the blocks in memory, the forged block must fail there
+	// too even though it never reached the database
+	view := make(memoryView)
+	if err := view.add(prev); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := c.NewBlockTemplate([]*transaction.Transaction{
+		coinbase(t, w, transaction.Reward),
+		spend(t, w, prev, []int{0}, -1000000, 1000100),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verifyTransactions(b, view); !errors.Is(err, transaction.ErrInvalidValue) {
+		t.Fatalf("verifyTransactions() = %v, want %v", err, transaction.ErrInvalidValue)
+	}
+}
+
+func TestTipReadWhileConnecting(t *testing.T) {
+	c, w := newTestChain(t)
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	defer func() {
+		close(done)
+		<-stopped
+	}()
+
+	// Run with -race: readers must not race with the blocks being connected
+	go func() {
+		defer close(stopped)
+
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			if _, err := c.Tip(); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if _, err := c.Iterator().Next(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 3; i++ {
+		coinbaseTx, err := transaction.CoinBase(string(w.Address()), "", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := c.AddBlock([]*transaction.Transaction{coinbaseTx})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(c.LastHash()) != string(b.Hash) {
+			t.Fatalf("LastHash() = %x, want %x", c.LastHash(), b.Hash)
+		}
+	}
+}
+
+func TestGenesisIsDeterministic(t *testing.T) {
+	genesis := func(network string, g config.Genesis) []byte {
+		cfg := config.Default()
+		cfg.DataDir = t.TempDir()
+		cfg.Network = network
+		cfg.Genesis = g
+
+		c, err := New(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Database.Close()
+
+		return c.LastHash()
+	}
+
+	for _, p := range []*params.Params{params.Main, params.Test} {
+		first := genesis(p.Name, config.Genesis{})
+		if second := genesis(p.Name, config.Genesis{}); string(first) != string(second) {
+			t.Fatalf("%s: genesis %x then %x with the default settings", p.Name, first, second)
+		}
+
+		if other := genesis(p.Name, config.Genesis{Message: "private network"}); string(other) == string(first) {
+			t.Fatalf("%s: overriding the genesis message kept genesis %x", p.Name, first)
+		}
+	}
+}
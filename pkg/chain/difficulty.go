@@ -0,0 +1,76 @@
+package chain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+)
+
+var ErrInvalidDifficulty = errors.New("invalid block difficulty")
+
+// NextBits returns the difficulty expected for the block built on top of
+// prev, which is nil for the genesis block. The difficulty is only adjusted
+// every RetargetInterval blocks, comparing the time the last interval took
+// with the time it was expected to take.
+func (c *Chain) NextBits(prev *block.Block) (uint32, error) {
+	if prev == nil {
+		return c.Params.InitialBits, nil
+	}
+
+	interval := c.Params.RetargetInterval
+	height := prev.Height + 1
+
+	if interval == 0 || height%interval != 0 {
+		return prev.Bits, nil
+	}
+
+	first := prev
+	for first.Height > height-interval && len(first.PrevHash) != 0 {
+		var err error
+		first, err = c.GetBlock(first.PrevHash)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	gaps := int64(prev.Height - first.Height)
+	if gaps == 0 {
+		return prev.Bits, nil
+	}
+
+	actual := prev.Timestamp - first.Timestamp
+	if actual < 1 {
+		actual = 1
+	}
+
+	expected := gaps * int64(c.Params.TargetSpacing/time.Second)
+	if expected < 1 {
+		expected = 1
+	}
+
+	bits := prev.Bits
+
+	// Every bit doubles or halves the work needed for a block
+	for steps := uint32(0); steps < c.Params.MaxAdjustment; steps++ {
+		if actual*2 <= expected {
+			bits++
+			actual *= 2
+		} else if actual >= expected*2 && bits > 0 {
+			bits--
+			actual /= 2
+		} else {
+			break
+		}
+	}
+
+	if bits < c.Params.MinBits {
+		bits = c.Params.MinBits
+	}
+
+	if bits > c.Params.MaxBits {
+		bits = c.Params.MaxBits
+	}
+
+	return bits, nil
+}
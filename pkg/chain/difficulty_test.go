@@ -0,0 +1,147 @@
+package chain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+	"github.com/herlon214/ipfs-blockchain/pkg/params"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+	"github.com/herlon214/ipfs-blockchain/pkg/wallets"
+)
+
+// mineAt mines a block on top of the best chain with the given timestamp.
+func mineAt(t *testing.T, c *Chain, w *wallets.Wallet, timestamp int64) *block.Block {
+	t.Helper()
+
+	coinbaseTx, err := transaction.CoinBase(string(w.Address()), "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := c.NewBlockTemplate([]*transaction.Transaction{coinbaseTx})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b.Timestamp = timestamp
+	if err := b.DeriveHash(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.ConnectBlock(b); err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+func TestNextBits(t *testing.T) {
+	c, w := newTestChain(t)
+
+	bits, err := c.NextBits(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bits != params.Test.InitialBits {
+		t.Fatalf("NextBits(nil) = %d, want %d", bits, params.Test.InitialBits)
+	}
+
+	tip, err := c.Tip()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev, err := c.GetBlock(tip.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every interval mines RetargetInterval blocks spaced by the given
+	// number of seconds, the target spacing being one second
+	intervals := []struct {
+		name    string
+		spacing int64
+		want    uint32
+	}{
+		{"too fast, raised by at most MaxAdjustment", 0, 2},
+		{"on target, unchanged", 1, 2},
+		{"too slow, lowered", 10, 1},
+		{"too slow at the minimum, clamped", 10, params.Test.MinBits},
+	}
+
+	interval := int(params.Test.RetargetInterval)
+
+	for _, tt := range intervals {
+		start := prev.Bits
+
+		for i := 1; i < interval; i++ {
+			prev = mineAt(t, c, w, prev.Timestamp+tt.spacing)
+
+			if prev.Bits != start {
+				t.Fatalf("%s: block %d has %d bits, want %d before the retarget", tt.name, prev.Height, prev.Bits, start)
+			}
+		}
+
+		bits, err := c.NextBits(prev)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if bits != tt.want {
+			t.Fatalf("%s: NextBits() at height %d = %d, want %d", tt.name, prev.Height+1, bits, tt.want)
+		}
+
+		prev = mineAt(t, c, w, prev.Timestamp+tt.spacing)
+
+		if prev.Bits != tt.want {
+			t.Fatalf("%s: retarget block has %d bits, want %d", tt.name, prev.Bits, tt.want)
+		}
+	}
+}
+
+func TestNextBitsClampsToMaxBits(t *testing.T) {
+	c, w := newTestChain(t)
+
+	p := *params.Test
+	p.MaxBits = p.InitialBits
+	c.Params = &p
+
+	var prev *block.Block
+	for i := uint64(1); i < p.RetargetInterval; i++ {
+		prev = mineAt(t, c, w, 1600000000)
+	}
+
+	bits, err := c.NextBits(prev)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bits != p.MaxBits {
+		t.Fatalf("NextBits() = %d, want %d", bits, p.MaxBits)
+	}
+}
+
+func TestConnectBlockRejectsWrongBits(t *testing.T) {
+	c, w := newTestChain(t)
+
+	coinbaseTx, err := transaction.CoinBase(string(w.Address()), "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := c.NewBlockTemplate([]*transaction.Transaction{coinbaseTx})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b.Bits++
+	if err := b.DeriveHash(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.ConnectBlock(b); !errors.Is(err, ErrInvalidDifficulty) {
+		t.Fatalf("ConnectBlock() error = %v, want %v", err, ErrInvalidDifficulty)
+	}
+}
@@ -0,0 +1,62 @@
+package chain
+
+import "github.com/herlon214/ipfs-blockchain/pkg/block"
+
+type EventType int
+
+const (
+	// BlockConnected is emitted when a block becomes part of the best chain
+	BlockConnected EventType = iota
+	// BlockDisconnected is emitted when a reorganization removes a block
+	// from the best chain
+	BlockDisconnected
+)
+
+func (t EventType) String() string {
+	switch t {
+	case BlockConnected:
+		return "connected"
+	case BlockDisconnected:
+		return "disconnected"
+	default:
+		return "unknown"
+	}
+}
+
+type Event struct {
+	Type  EventType
+	Block *block.Block
+}
+
+// Subscribe calls fn with every block connected to or disconnected from the
+// best chain, in order. On a reorganization the disconnected blocks come
+// first, newest first, followed by the connected ones, oldest first. fn runs
+// while blocks are being connected, so it must not connect blocks itself.
+func (c *Chain) Subscribe(fn func(e Event)) (unsubscribe func()) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	id := c.nextSubscriber
+	c.nextSubscriber++
+	c.subscribers[id] = fn
+
+	return func() {
+		c.mx.Lock()
+		defer c.mx.Unlock()
+
+		delete(c.subscribers, id)
+	}
+}
+
+func (c *Chain) emit(e Event) {
+	c.mx.Lock()
+	subscribers := make([]func(Event), 0, len(c.subscribers))
+	for _, fn := range c.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	c.mx.Unlock()
+
+	for _, fn := range subscribers {
+		fn(e)
+	}
+}
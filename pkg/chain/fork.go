@@ -0,0 +1,382 @@
+package chain
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/dgraph-io/badger"
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+)
+
+var (
+	workPrefix = []byte("work-")
+	tipPrefix  = []byte("tip-")
+	undoPrefix = []byte("undo-")
+)
+
+var ErrReorgTooDeep = errors.New("reorganization is deeper than the limit")
+
+// Tip is the last block of a branch.
+type Tip struct {
+	Hash   []byte
+	Height uint64
+	// Work is the cumulative work of the branch up to the tip
+	Work *big.Int
+	// Best reports whether the tip is the last block of the best chain
+	Best bool
+}
+
+// ConnectBlock validates a mined block and stores it along with its
+// cumulative work. Blocks whose parent is unknown are rejected with
+// ErrBrokenLink, blocks breaking the consensus rules with a VerifyError
+// matching ErrInvalidBlock. When the block makes its branch heavier than the best chain
+// the chain is reorganized to it, disconnecting the blocks of the best chain
+// back to the fork point and connecting the ones of the new branch in a
+// single database transaction. Blocks containing transactions that fail
+// verification are rejected, along with the branch blocks built on them.
+func (c *Chain) ConnectBlock(b *block.Block) error {
+	c.connectMx.Lock()
+	defer c.connectMx.Unlock()
+
+	if c.HasBlock(b.Hash) {
+		return nil
+	}
+
+	parent, err := c.GetBlock(b.PrevHash)
+	if err != nil {
+		if errors.Is(err, ErrBlockNotFound) {
+			return fmt.Errorf("block %x: %w: parent %x is unknown", b.Hash, ErrBrokenLink, b.PrevHash)
+		}
+
+		return err
+	}
+
+	timestamps, err := c.recentTimestamps(parent)
+	if err != nil {
+		return err
+	}
+
+	bits, err := c.NextBits(parent)
+	if err != nil {
+		return err
+	}
+
+	if err := validateHeader(b, parent, bits, timestamps, time.Now()); err != nil {
+		return &VerifyError{Hash: b.Hash, Height: int(b.Height), Err: err}
+	}
+
+	parentWork, err := c.Work(parent.Hash)
+	if err != nil {
+		return err
+	}
+
+	bestWork, err := c.Work(c.LastHash())
+	if err != nil {
+		return err
+	}
+
+	work := new(big.Int).Add(parentWork, BlockWork(b.Bits))
+
+	// Side branches are only stored until they become the heaviest
+	if work.Cmp(bestWork) <= 0 {
+		return c.Database.Update(func(txn *badger.Txn) error {
+			return storeBlock(txn, b, work)
+		})
+	}
+
+	return c.reorganize(b, parent, work)
+}
+
+// reorganize makes b, whose branch has the given work, the new best chain
+// tip.
+func (c *Chain) reorganize(b *block.Block, parent *block.Block, work *big.Int) error {
+	disconnect, connect, err := c.findFork(parent)
+	if err != nil {
+		return err
+	}
+
+	// Newest first, like disconnect
+	connect = append([]*block.Block{b}, connect...)
+
+	var invalid int
+	err = c.Database.Update(func(txn *badger.Txn) error {
+		if err := storeBlock(txn, b, work); err != nil {
+			return err
+		}
+
+		for _, old := range disconnect {
+			undo, err := getUndo(txn, old.Hash)
+			if err != nil {
+				return err
+			}
+
+			if err := disconnectTransactions(txn, old, undo); err != nil {
+				return err
+			}
+
+			if err := txn.Delete(undoKey(old.Hash)); err != nil {
+				return err
+			}
+		}
+
+		for i := len(connect) - 1; i >= 0; i-- {
+			view := &txnView{txn: txn}
+
+			if err := verifyTransactions(connect[i], view); err != nil {
+				invalid = i
+
+				return &VerifyError{Hash: connect[i].Hash, Height: int(connect[i].Height), Err: err}
+			}
+
+			if err := setUndo(txn, connect[i].Hash, view.undo); err != nil {
+				return err
+			}
+		}
+
+		return txn.Set([]byte("lh"), b.Hash)
+	})
+	if err != nil {
+		// b was not stored, but the side branch blocks it was built on
+		// must be dropped when one of them is invalid
+		if invalid > 0 {
+			if dropErr := c.dropBranch(connect[1 : invalid+1]); dropErr != nil {
+				return dropErr
+			}
+		}
+
+		return err
+	}
+
+	c.setLastHash(b.Hash)
+
+	for _, old := range disconnect {
+		c.emit(Event{Type: BlockDisconnected, Block: old})
+	}
+
+	for i := len(connect) - 1; i >= 0; i-- {
+		c.emit(Event{Type: BlockConnected, Block: connect[i]})
+	}
+
+	c.notifyTipChanged()
+
+	return nil
+}
+
+// findFork walks the best chain and the branch ending at tip back to their
+// common block, returning the blocks of each one after it, newest first.
+func (c *Chain) findFork(tip *block.Block) ([]*block.Block, []*block.Block, error) {
+	var disconnect, connect []*block.Block
+
+	old, err := c.GetBlock(c.LastHash())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	branch := tip
+
+	for !bytes.Equal(old.Hash, branch.Hash) {
+		if old.Height >= branch.Height {
+			if uint64(len(disconnect)) >= c.Params.MaxReorgDepth {
+				return nil, nil, fmt.Errorf("%w: more than %d blocks", ErrReorgTooDeep, c.Params.MaxReorgDepth)
+			}
+
+			disconnect = append(disconnect, old)
+
+			old, err = c.GetBlock(old.PrevHash)
+			if err != nil {
+				return nil, nil, err
+			}
+		} else {
+			connect = append(connect, branch)
+
+			branch, err = c.GetBlock(branch.PrevHash)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	return disconnect, connect, nil
+}
+
+// dropBranch deletes side branch blocks that turned out to be invalid, making
+// the parent of the oldest one a tip again.
+func (c *Chain) dropBranch(blocks []*block.Block) error {
+	return c.Database.Update(func(txn *badger.Txn) error {
+		for _, b := range blocks {
+			for _, key := range [][]byte{blockKey(b.Hash), workKey(b.Hash), tipKey(b.Hash)} {
+				if err := txn.Delete(key); err != nil {
+					return err
+				}
+			}
+		}
+
+		return txn.Set(tipKey(blocks[len(blocks)-1].PrevHash), []byte{})
+	})
+}
+
+// Tip returns the last block of the best chain.
+func (c *Chain) Tip() (Tip, error) {
+	hash := c.LastHash()
+
+	b, err := c.GetBlock(hash)
+	if err != nil {
+		return Tip{}, err
+	}
+
+	work, err := c.Work(hash)
+	if err != nil {
+		return Tip{}, err
+	}
+
+	return Tip{Hash: hash, Height: b.Height, Work: work, Best: true}, nil
+}
+
+// Tips returns the last block of every branch stored.
+func (c *Chain) Tips() ([]Tip, error) {
+	var hashes [][]byte
+
+	err := c.Database.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(tipPrefix); it.ValidForPrefix(tipPrefix); it.Next() {
+			hashes = append(hashes, bytes.TrimPrefix(it.Item().KeyCopy(nil), tipPrefix))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	best := c.LastHash()
+
+	tips := make([]Tip, 0, len(hashes))
+	for _, hash := range hashes {
+		b, err := c.GetBlock(hash)
+		if err != nil {
+			return nil, err
+		}
+
+		work, err := c.Work(hash)
+		if err != nil {
+			return nil, err
+		}
+
+		tips = append(tips, Tip{
+			Hash:   hash,
+			Height: b.Height,
+			Work:   work,
+			Best:   bytes.Equal(hash, best),
+		})
+	}
+
+	return tips, nil
+}
+
+// BlockWork is the expected number of hashes needed to mine a block with
+// the given difficulty.
+func BlockWork(bits uint32) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(bits))
+}
+
+// storeBlock saves b with its cumulative work as the tip of its branch.
+func storeBlock(txn *badger.Txn, b *block.Block, work *big.Int) error {
+	data, err := b.Serialize()
+	if err != nil {
+		return err
+	}
+
+	err = txn.Set(blockKey(b.Hash), data)
+	if err != nil {
+		return err
+	}
+
+	err = txn.Set(workKey(b.Hash), work.Bytes())
+	if err != nil {
+		return err
+	}
+
+	err = txn.Delete(tipKey(b.PrevHash))
+	if err != nil {
+		return err
+	}
+
+	return txn.Set(tipKey(b.Hash), []byte{})
+}
+
+// Work returns the cumulative work of the branch ending at the block with
+// the given hash.
+func (c *Chain) Work(hash []byte) (*big.Int, error) {
+	work := new(big.Int)
+
+	err := c.Database.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(workKey(hash))
+		if err != nil {
+			if err == badger.ErrKeyNotFound {
+				return fmt.Errorf("%w: block %x has no cumulative work", ErrCorruptData, hash)
+			}
+
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			work.SetBytes(val)
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return work, nil
+}
+
+func getUndo(txn *badger.Txn, hash []byte) ([]spentOutput, error) {
+	item, err := txn.Get(undoKey(hash))
+	if err != nil {
+		if err == badger.ErrKeyNotFound {
+			return nil, fmt.Errorf("%w: block %x has no undo data", ErrCorruptData, hash)
+		}
+
+		return nil, err
+	}
+
+	var undo []spentOutput
+	err = item.Value(func(val []byte) error {
+		return gob.NewDecoder(bytes.NewReader(val)).Decode(&undo)
+	})
+
+	return undo, err
+}
+
+func setUndo(txn *badger.Txn, hash []byte, undo []spentOutput) error {
+	var buf bytes.Buffer
+
+	if err := gob.NewEncoder(&buf).Encode(undo); err != nil {
+		return err
+	}
+
+	return txn.Set(undoKey(hash), buf.Bytes())
+}
+
+func workKey(hash []byte) []byte {
+	return bytes.Join([][]byte{workPrefix, hash}, []byte{})
+}
+
+func tipKey(hash []byte) []byte {
+	return bytes.Join([][]byte{tipPrefix, hash}, []byte{})
+}
+
+func undoKey(hash []byte) []byte {
+	return bytes.Join([][]byte{undoPrefix, hash}, []byte{})
+}
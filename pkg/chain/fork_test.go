@@ -0,0 +1,284 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+	"github.com/herlon214/ipfs-blockchain/pkg/params"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+	"github.com/herlon214/ipfs-blockchain/pkg/wallets"
+)
+
+// mineBranch mines n blocks on top of parent, spacing their timestamps by
+// the given number of seconds, and connects them. The first block also holds
+// the given transactions. Every coinbase pays miner. The test network
+// raises the difficulty of fast blocks every RetargetInterval blocks, so
+// fast branches gather more work than slow ones of the same length.
+// Connecting stops at the first error, which is returned along with the
+// blocks connected before it.
+func mineBranch(t *testing.T, c *Chain, parent *block.Block, n int, spacing int64, miner *wallets.Wallet, txs ...*transaction.Transaction) ([]*block.Block, error) {
+	t.Helper()
+
+	var blocks []*block.Block
+
+	for i := 0; i < n; i++ {
+		coinbaseTx, err := transaction.CoinBase(string(miner.Address()), fmt.Sprintf("%x %d", parent.Hash, i), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		bits, err := c.NextBits(parent)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b := block.New(append([]*transaction.Transaction{coinbaseTx}, txs...), parent.Hash, parent.Height+1, bits)
+		b.Timestamp = parent.Timestamp + spacing
+
+		if err := b.DeriveHash(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := c.ConnectBlock(b); err != nil {
+			return blocks, err
+		}
+
+		blocks = append(blocks, b)
+		parent = b
+		txs = nil
+	}
+
+	return blocks, nil
+}
+
+func genesisBlock(t *testing.T, c *Chain) *block.Block {
+	t.Helper()
+
+	b, err := c.BlockAtHeight(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+func newWallets(t *testing.T, n int) []*wallets.Wallet {
+	t.Helper()
+
+	var ws []*wallets.Wallet
+	for i := 0; i < n; i++ {
+		w, err := wallets.NewWallet()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ws = append(ws, w)
+	}
+
+	return ws
+}
+
+// assertBalances checks the UTXO set through the balance of each wallet.
+func assertBalances(t *testing.T, c *Chain, want map[*wallets.Wallet]int) {
+	t.Helper()
+
+	for w, value := range want {
+		balance, err := c.Balance(w.PublicKeyHash())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if balance != value {
+			t.Errorf("balance of %s = %d, want %d", w.Address(), balance, value)
+		}
+	}
+
+	if err := c.Verify(context.Background()); err != nil {
+		t.Fatalf("Verify() = %v", err)
+	}
+}
+
+func assertTip(t *testing.T, c *Chain, want *block.Block) {
+	t.Helper()
+
+	tip, err := c.Tip()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(tip.Hash, want.Hash) {
+		t.Fatalf("tip %x at height %d, want %x at height %d", tip.Hash, tip.Height, want.Hash, want.Height)
+	}
+}
+
+func TestReorganizeToHeavierShorterBranch(t *testing.T) {
+	c, w := newTestChain(t)
+	ws := newWallets(t, 3)
+	to, minerA, minerB := ws[0], ws[1], ws[2]
+
+	genesis := genesisBlock(t, c)
+
+	payment, err := c.NewTransaction(w, string(to.Address()), 60, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Branch A is longer but its slow blocks keep the minimum difficulty
+	branchA, err := mineBranch(t, c, genesis, 7, 10, minerA, payment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertTip(t, c, branchA[len(branchA)-1])
+	assertBalances(t, c, map[*wallets.Wallet]int{
+		w:      transaction.Reward - 60,
+		to:     60,
+		minerA: 7 * transaction.Reward,
+	})
+
+	var events []Event
+	c.Subscribe(func(e Event) {
+		events = append(events, e)
+	})
+
+	// Branch B is shorter but its fast blocks raise the difficulty
+	branchB, err := mineBranch(t, c, genesis, 6, 0, minerB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workA := new(big.Int).Add(BlockWork(genesis.Bits), new(big.Int).Mul(big.NewInt(7), BlockWork(params.Test.MinBits)))
+	tip, err := c.Tip()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tip.Work.Cmp(workA) <= 0 {
+		t.Fatalf("branch B work %s, not above branch A work %s", tip.Work, workA)
+	}
+
+	assertTip(t, c, branchB[len(branchB)-1])
+
+	// The payment and the rewards of branch A are undone, the genesis
+	// output it spent is restored from the undo data
+	assertBalances(t, c, map[*wallets.Wallet]int{
+		w:      transaction.Reward,
+		to:     0,
+		minerA: 0,
+		minerB: 6 * transaction.Reward,
+	})
+
+	// Branch A is disconnected newest first, then branch B is connected
+	// oldest first, so the disconnected transactions can go back to the
+	// mempool
+	var want []string
+	for i := len(branchA) - 1; i >= 0; i-- {
+		want = append(want, fmt.Sprintf("disconnected %x", branchA[i].Hash))
+	}
+	for _, b := range branchB {
+		want = append(want, fmt.Sprintf("connected %x", b.Hash))
+	}
+
+	var got []string
+	for _, e := range events {
+		got = append(got, fmt.Sprintf("%s %x", e.Type, e.Block.Hash))
+	}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("events %v, want %v", got, want)
+	}
+
+	if returned := events[len(branchA)-1].Block; len(returned.Transactions) != 2 || !bytes.Equal(returned.Transactions[1].ID, payment.ID) {
+		t.Fatal("the payment is not in the disconnected blocks")
+	}
+
+	// The payment can be mined again on the new best chain
+	if _, err := mineBranch(t, c, branchB[len(branchB)-1], 1, 0, minerB, payment); err != nil {
+		t.Fatal(err)
+	}
+
+	assertBalances(t, c, map[*wallets.Wallet]int{
+		w:  transaction.Reward - 60,
+		to: 60,
+	})
+}
+
+func TestReorganizeRejectsTooDeep(t *testing.T) {
+	c, w := newTestChain(t)
+	ws := newWallets(t, 3)
+	to, minerA, minerB := ws[0], ws[1], ws[2]
+
+	genesis := genesisBlock(t, c)
+
+	payment, err := c.NewTransaction(w, string(to.Address()), 60, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	depth := int(params.Test.MaxReorgDepth)
+
+	branchA, err := mineBranch(t, c, genesis, depth+1, 10, minerA, payment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Branch B gets heavier than branch A, switching to it would
+	// disconnect more than MaxReorgDepth blocks
+	branchB, err := mineBranch(t, c, genesis, depth+1, 0, minerB)
+	if !errors.Is(err, ErrReorgTooDeep) {
+		t.Fatalf("ConnectBlock() error = %v, want %v", err, ErrReorgTooDeep)
+	}
+
+	if len(branchB) == 0 {
+		t.Fatal("branch B was rejected before getting heavier")
+	}
+
+	assertTip(t, c, branchA[len(branchA)-1])
+	assertBalances(t, c, map[*wallets.Wallet]int{
+		w:      transaction.Reward - 60,
+		to:     60,
+		minerA: (depth + 1) * transaction.Reward,
+		minerB: 0,
+	})
+}
+
+func TestReorganizeDropsInvalidBranch(t *testing.T) {
+	c, w := newTestChain(t)
+	ws := newWallets(t, 2)
+	minerA, minerB := ws[0], ws[1]
+
+	genesis := genesisBlock(t, c)
+
+	branchA, err := mineBranch(t, c, genesis, 7, 10, minerA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Side branch blocks are stored without checking their transactions,
+	// which fail once the branch gets heavier
+	invalid := spend(t, w, genesisCoinbase(t, c), []int{0}, transaction.Reward+1)
+
+	branchB, err := mineBranch(t, c, genesis, 6, 0, minerB, invalid)
+	if !errors.Is(err, ErrInvalidBlock) || !errors.Is(err, ErrInvalidTransaction) {
+		t.Fatalf("ConnectBlock() error = %v, want %v", err, ErrInvalidTransaction)
+	}
+
+	assertTip(t, c, branchA[len(branchA)-1])
+
+	for _, b := range branchB {
+		if c.HasBlock(b.Hash) {
+			t.Fatalf("block %x of the invalid branch kept", b.Hash)
+		}
+	}
+
+	assertBalances(t, c, map[*wallets.Wallet]int{
+		w:      transaction.Reward,
+		minerA: 7 * transaction.Reward,
+		minerB: 0,
+	})
+}
@@ -0,0 +1,126 @@
+package chain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+)
+
+const (
+	// MaxFutureBlockTime is how far ahead of the local clock a block
+	// timestamp is accepted
+	MaxFutureBlockTime = 2 * time.Hour
+	// MedianTimeBlocks is the number of previous blocks used to compute the
+	// minimum timestamp accepted for a new block
+	MedianTimeBlocks = 11
+)
+
+var (
+	ErrInvalidHeader    = errors.New("invalid block header")
+	ErrInvalidTimestamp = errors.New("invalid block timestamp")
+)
+
+// validateHeader checks the header of b against its parent prev, which is nil
+// for the genesis block, and the difficulty expected for its height.
+// timestamps holds the timestamps of the last blocks up to prev, used to
+// reject blocks older than their median.
+func validateHeader(b *block.Block, prev *block.Block, bits uint32, timestamps []int64, now time.Time) error {
+	if b.Version != block.Version {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidHeader, b.Version)
+	}
+
+	if prev == nil {
+		if b.Height != 0 || len(b.PrevHash) != 0 {
+			return fmt.Errorf("%w: genesis block must have height 0 and no parent", ErrInvalidHeader)
+		}
+	} else {
+		if !bytes.Equal(b.PrevHash, prev.Hash) {
+			return ErrBrokenLink
+		}
+
+		if b.Height != prev.Height+1 {
+			return fmt.Errorf("%w: height %d does not follow parent height %d", ErrInvalidHeader, b.Height, prev.Height)
+		}
+	}
+
+	if b.Bits != bits {
+		return fmt.Errorf("%w: got %d bits, expected %d", ErrInvalidDifficulty, b.Bits, bits)
+	}
+
+	// The merkle tree pairs the last node of odd levels with itself, so
+	// repeating the last transactions of a block keeps its merkle root
+	seen := make(map[string]bool, len(b.Transactions))
+	for _, tx := range b.Transactions {
+		id := hex.EncodeToString(tx.ID)
+		if seen[id] {
+			return fmt.Errorf("%w: %s: duplicate transaction", ErrInvalidTransaction, id)
+		}
+		seen[id] = true
+	}
+
+	if !bytes.Equal(b.MerkleRoot, b.HashTransactions()) {
+		return fmt.Errorf("%w: merkle root does not match the transactions", ErrInvalidHeader)
+	}
+
+	pow := block.NewProof(b)
+	if !pow.Validate() {
+		return ErrInvalidProof
+	}
+
+	hash := sha256.Sum256(pow.InitData(b.Nonce))
+	if !bytes.Equal(hash[:], b.Hash) {
+		return ErrHashMismatch
+	}
+
+	if b.Timestamp > now.Add(MaxFutureBlockTime).Unix() {
+		return fmt.Errorf("%w: %d is too far in the future", ErrInvalidTimestamp, b.Timestamp)
+	}
+
+	if len(timestamps) > 0 {
+		median := medianTimestamp(timestamps)
+		if b.Timestamp < median {
+			return fmt.Errorf("%w: %d is earlier than the median %d of the previous blocks", ErrInvalidTimestamp, b.Timestamp, median)
+		}
+	}
+
+	return nil
+}
+
+// recentTimestamps returns the timestamps of the last MedianTimeBlocks blocks
+// ending at b, oldest first.
+func (c *Chain) recentTimestamps(b *block.Block) ([]int64, error) {
+	timestamps := make([]int64, 0, MedianTimeBlocks)
+
+	for b != nil && len(timestamps) < MedianTimeBlocks {
+		timestamps = append([]int64{b.Timestamp}, timestamps...)
+
+		if len(b.PrevHash) == 0 {
+			break
+		}
+
+		var err error
+		b, err = c.GetBlock(b.PrevHash)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return timestamps, nil
+}
+
+func medianTimestamp(timestamps []int64) int64 {
+	sorted := make([]int64, len(timestamps))
+	copy(sorted, timestamps)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	return sorted[len(sorted)/2]
+}
@@ -0,0 +1,67 @@
+package chain
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+)
+
+func TestConnectBlockRejectsDuplicateTransactions(t *testing.T) {
+	c, w := newTestChain(t)
+	miner := newWallets(t, 1)[0]
+
+	genesis := genesisBlock(t, c)
+
+	branch, err := mineBranch(t, c, genesis, 2, 10, miner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The side block is lighter than the best chain, its transactions are
+	// only checked once its branch gets heavier
+	paid := spend(t, w, genesisCoinbase(t, c), []int{0}, 60, 40)
+	change := spend(t, w, paid, []int{1}, 40)
+
+	valid := block.New([]*transaction.Transaction{coinbase(t, w, transaction.Reward), paid, change}, genesis.Hash, 1, genesis.Bits)
+	valid.Timestamp = genesis.Timestamp + 10
+
+	if err := valid.DeriveHash(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Repeating the last transaction of an odd level keeps the merkle root,
+	// so the mutated block has the same hash as the valid one
+	mutated := *valid
+	mutated.Transactions = append(valid.Transactions[:3:3], change)
+
+	if !bytes.Equal(mutated.HashTransactions(), valid.MerkleRoot) {
+		t.Fatal("mutated block has a different merkle root")
+	}
+
+	if err := c.ConnectBlock(&mutated); !errors.Is(err, ErrInvalidBlock) || !errors.Is(err, ErrInvalidTransaction) {
+		t.Fatalf("ConnectBlock() error = %v, want %v and %v", err, ErrInvalidBlock, ErrInvalidTransaction)
+	}
+
+	if c.HasBlock(valid.Hash) {
+		t.Fatal("mutated block stored")
+	}
+
+	// The valid block sharing its hash can still be stored
+	if err := c.ConnectBlock(valid); err != nil {
+		t.Fatalf("ConnectBlock() = %v", err)
+	}
+
+	stored, err := c.GetBlock(valid.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stored.Transactions) != len(valid.Transactions) {
+		t.Fatalf("stored block has %d transactions, want %d", len(stored.Transactions), len(valid.Transactions))
+	}
+
+	assertTip(t, c, branch[len(branch)-1])
+}
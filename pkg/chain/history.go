@@ -0,0 +1,144 @@
+package chain
+
+import (
+	"fmt"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+)
+
+const (
+	DirectionIn  = "in"
+	DirectionOut = "out"
+)
+
+// HistoryEntry describes a transaction that paid to or spent from an address.
+type HistoryEntry struct {
+	TxID      []byte
+	BlockHash []byte
+	Height    int
+	Direction string
+	// Amount is the value received for incoming transactions and the value
+	// sent to other addresses, fees included, for outgoing ones.
+	Amount int
+}
+
+type historyCandidate struct {
+	tx        *transaction.Transaction
+	blockHash []byte
+	height    int
+}
+
+// History returns the transactions involving the given public key hash, most
+// recent first. A limit lower or equal to zero returns every transaction.
+func (c *Chain) History(pubKeyHash []byte, limit int) ([]HistoryEntry, error) {
+	var candidates []historyCandidate
+	owned := make(map[string]int)
+
+	it := c.Iterator()
+	for {
+		b, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		if b == nil {
+			break
+		}
+
+		for i := len(b.Transactions) - 1; i >= 0; i-- {
+			tx := b.Transactions[i]
+			involved := false
+
+			for idx, out := range tx.Outputs {
+				if out.IsLockedWithKey(pubKeyHash) {
+					owned[outpointKey(tx.ID, idx)] = out.Value
+					involved = true
+				}
+			}
+
+			if !tx.IsCoinBase() {
+				for _, in := range tx.Inputs {
+					if in.UsesKey(pubKeyHash) {
+						involved = true
+					}
+				}
+			}
+
+			if involved {
+				candidates = append(candidates, historyCandidate{
+					tx:        tx,
+					blockHash: b.Hash,
+					height:    int(b.Height),
+				})
+			}
+		}
+	}
+
+	var entries []HistoryEntry
+	for _, candidate := range candidates {
+		if limit > 0 && len(entries) >= limit {
+			break
+		}
+
+		received := 0
+		for idx, out := range candidate.tx.Outputs {
+			if out.IsLockedWithKey(pubKeyHash) {
+				received += owned[outpointKey(candidate.tx.ID, idx)]
+			}
+		}
+
+		spent := 0
+		if !candidate.tx.IsCoinBase() {
+			for _, in := range candidate.tx.Inputs {
+				if in.UsesKey(pubKeyHash) {
+					spent += owned[outpointKey(in.ID, in.Out)]
+				}
+			}
+		}
+
+		entry := HistoryEntry{
+			TxID:      candidate.tx.ID,
+			BlockHash: candidate.blockHash,
+			Height:    candidate.height,
+			Direction: DirectionIn,
+			Amount:    received,
+		}
+
+		if spent > 0 {
+			entry.Direction = DirectionOut
+			entry.Amount = spent - received
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// UsedKeys returns the public key hashes that received an output in the best
+// chain, as map keys.
+func (c *Chain) UsedKeys() (map[string]bool, error) {
+	used := make(map[string]bool)
+
+	it := c.Iterator()
+	for {
+		b, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		if b == nil {
+			return used, nil
+		}
+
+		for _, tx := range b.Transactions {
+			for _, out := range tx.Outputs {
+				used[string(out.PubKeyHash)] = true
+			}
+		}
+	}
+}
+
+func outpointKey(txID []byte, idx int) string {
+	return fmt.Sprintf("%x:%d", txID, idx)
+}
@@ -0,0 +1,42 @@
+package chain
+
+import (
+	"bytes"
+
+	"github.com/dgraph-io/badger"
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+)
+
+type Iterator struct {
+	CurrentHash []byte
+
+	Database *badger.DB
+}
+
+func (c *Chain) Iterator() *Iterator {
+	return &Iterator{
+		CurrentHash: c.LastHash(),
+		Database:    c.Database,
+	}
+}
+
+// Next returns the current block and moves the iterator to its parent.
+// It returns nil once the genesis block has been visited.
+func (it *Iterator) Next() (*block.Block, error) {
+	if bytes.Equal(it.CurrentHash, []byte{}) {
+		return nil, nil
+	}
+
+	currentBlock, err := getBlock(it.Database, it.CurrentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	it.CurrentHash = currentBlock.PrevHash
+
+	return currentBlock, nil
+}
+
+func blockKey(hash []byte) []byte {
+	return bytes.Join([][]byte{blockPrefix, hash}, []byte{})
+}
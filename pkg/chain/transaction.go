@@ -0,0 +1,105 @@
+package chain
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+	"github.com/herlon214/ipfs-blockchain/pkg/wallets"
+)
+
+// NewTransaction builds and signs a transaction moving amount coins from the
+// wallet to the given address. The difference between the selected outputs
+// and amount plus fee is sent back to the wallet as change.
+func (c *Chain) NewTransaction(w *wallets.Wallet, to string, amount int, fee int) (*transaction.Transaction, error) {
+	utxos, err := c.ListUTXO(w.PublicKeyHash())
+	if err != nil {
+		return nil, err
+	}
+
+	return BuildTransaction(w, utxos, to, amount, fee)
+}
+
+// BuildTransaction builds and signs a transaction moving amount coins from
+// the wallet to the given address, spending the unspent outputs of the
+// wallet in the given order until they cover amount plus fee. The rest is
+// sent back to the wallet as change.
+func BuildTransaction(w *wallets.Wallet, utxos []UTXO, to string, amount int, fee int) (*transaction.Transaction, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("amount must be positive, got %d", amount)
+	}
+
+	if fee < 0 {
+		return nil, fmt.Errorf("fee must not be negative, got %d", fee)
+	}
+
+	toOut, err := transaction.NewOutput(amount, to)
+	if err != nil {
+		return nil, fmt.Errorf("recipient %s: %w", to, err)
+	}
+
+	need, err := transaction.AddValues(amount, fee)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the spent outputs are needed to sign the inputs
+	prevTxs := make(map[string]transaction.Transaction)
+
+	var inputs []transaction.Input
+	acc := 0
+
+	for _, utxo := range utxos {
+		if acc >= need {
+			break
+		}
+
+		if !utxo.Output.IsLockedWithKey(w.PublicKeyHash()) {
+			continue
+		}
+
+		inputs = append(inputs, transaction.Input{
+			ID:     utxo.TxID,
+			Out:    utxo.Index,
+			PubKey: w.PublicKey,
+		})
+		acc, err = transaction.AddValues(acc, utxo.Output.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		id := hex.EncodeToString(utxo.TxID)
+		prevTx := prevTxs[id]
+		prevTx.ID = utxo.TxID
+		for len(prevTx.Outputs) <= utxo.Index {
+			prevTx.Outputs = append(prevTx.Outputs, transaction.Output{})
+		}
+		prevTx.Outputs[utxo.Index] = utxo.Output
+		prevTxs[id] = prevTx
+	}
+
+	if acc < need {
+		return nil, fmt.Errorf("%w: have %d, need %d", ErrInsufficientFunds, acc, need)
+	}
+
+	outputs := []transaction.Output{*toOut}
+	if acc > need {
+		changeOut, err := transaction.NewOutput(acc-need, string(w.Address()))
+		if err != nil {
+			return nil, err
+		}
+
+		outputs = append(outputs, *changeOut)
+	}
+
+	tx, err := transaction.New(inputs, outputs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Sign(w.PrivateKey, prevTxs); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
@@ -0,0 +1,74 @@
+package chain
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+)
+
+func TestBuildTransaction(t *testing.T) {
+	ws := newWallets(t, 2)
+	w, to := ws[0], ws[1]
+
+	utxos := func(values ...int) []UTXO {
+		var result []UTXO
+		for i, value := range values {
+			result = append(result, UTXO{
+				TxID:   []byte{byte(i)},
+				Index:  0,
+				Output: transaction.Output{Value: value, PubKeyHash: w.PublicKeyHash()},
+			})
+		}
+
+		return result
+	}
+
+	tests := []struct {
+		name    string
+		utxos   []UTXO
+		amount  int
+		fee     int
+		outputs []int
+		match   error
+	}{
+		{"exact amount", utxos(60, 40), 99, 1, []int{99}, nil},
+		{"change", utxos(60, 40, 30), 80, 1, []int{80, 19}, nil},
+		{"insufficient funds", utxos(60, 40), 100, 1, nil, ErrInsufficientFunds},
+		{"amount plus fee overflow", utxos(60), math.MaxInt, 1, nil, transaction.ErrValueOverflow},
+		{"inputs overflow", utxos(math.MaxInt-1, 2, 1), math.MaxInt - 1, 1, nil, transaction.ErrValueOverflow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := BuildTransaction(w, tt.utxos, string(to.Address()), tt.amount, tt.fee)
+			if tt.match != nil {
+				if !errors.Is(err, tt.match) {
+					t.Fatalf("BuildTransaction() error = %v, want %v", err, tt.match)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var outputs []int
+			for _, out := range tx.Outputs {
+				outputs = append(outputs, out.Value)
+			}
+
+			if len(outputs) != len(tt.outputs) {
+				t.Fatalf("outputs %v, want %v", outputs, tt.outputs)
+			}
+
+			for i := range outputs {
+				if outputs[i] != tt.outputs[i] {
+					t.Fatalf("outputs %v, want %v", outputs, tt.outputs)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package chain
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/hex"
+	"sort"
+
+	"github.com/dgraph-io/badger"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+)
+
+// UnspentOutputs holds the outputs of a single transaction that were not
+// spent yet, keyed by their index inside the transaction.
+type UnspentOutputs map[int]transaction.Output
+
+func (outs UnspentOutputs) Serialize() ([]byte, error) {
+	var res bytes.Buffer
+
+	encoder := gob.NewEncoder(&res)
+	err := encoder.Encode(outs)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Bytes(), nil
+}
+
+func DeserializeOutputs(data []byte) (UnspentOutputs, error) {
+	var outs UnspentOutputs
+
+	decoder := gob.NewDecoder(bytes.NewReader(data))
+	err := decoder.Decode(&outs)
+	if err != nil {
+		return nil, err
+	}
+
+	return outs, nil
+}
+
+// FindUTXO returns every unspent output locked to the given public key hash.
+func (c *Chain) FindUTXO(pubKeyHash []byte) ([]transaction.Output, error) {
+	var result []transaction.Output
+
+	err := c.forEachUTXO(func(txID []byte, outs UnspentOutputs) bool {
+		for _, out := range outs {
+			if out.IsLockedWithKey(pubKeyHash) {
+				result = append(result, out)
+			}
+		}
+
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UTXO is an unspent output along with the outpoint spending it refers to.
+type UTXO struct {
+	TxID   []byte
+	Index  int
+	Output transaction.Output
+}
+
+// ListUTXO returns every unspent output locked to the given public key hash,
+// ordered by transaction ID and index.
+func (c *Chain) ListUTXO(pubKeyHash []byte) ([]UTXO, error) {
+	var result []UTXO
+
+	err := c.forEachUTXO(func(txID []byte, outs UnspentOutputs) bool {
+		for idx, out := range outs {
+			if out.IsLockedWithKey(pubKeyHash) {
+				result = append(result, UTXO{TxID: txID, Index: idx, Output: out})
+			}
+		}
+
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys are iterated in order, only the indexes of a map need sorting
+	sort.Slice(result, func(i, j int) bool {
+		if cmp := bytes.Compare(result[i].TxID, result[j].TxID); cmp != 0 {
+			return cmp < 0
+		}
+
+		return result[i].Index < result[j].Index
+	})
+
+	return result, nil
+}
+
+// FindSpendableOutputs collects unspent outputs locked to the given public key
+// hash until their sum reaches amount. It returns the accumulated value and
+// the selected output indexes keyed by the hex encoded transaction ID.
+func (c *Chain) FindSpendableOutputs(pubKeyHash []byte, amount int) (int, map[string][]int, error) {
+	accumulated := 0
+	unspent := make(map[string][]int)
+
+	err := c.forEachUTXO(func(txID []byte, outs UnspentOutputs) bool {
+		id := hex.EncodeToString(txID)
+
+		for idx, out := range outs {
+			if accumulated >= amount {
+				return false
+			}
+
+			if out.IsLockedWithKey(pubKeyHash) {
+				accumulated += out.Value
+				unspent[id] = append(unspent[id], idx)
+			}
+		}
+
+		return accumulated < amount
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return accumulated, unspent, nil
+}
+
+// UnspentOutput returns the output at idx of the transaction with the given
+// ID, reporting false when it does not exist or was already spent.
+func (c *Chain) UnspentOutput(txID []byte, idx int) (transaction.Output, bool, error) {
+	var out transaction.Output
+	var ok bool
+
+	err := c.Database.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(utxoKey(txID))
+		if err != nil {
+			if err == badger.ErrKeyNotFound {
+				return nil
+			}
+
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			outs, err := DeserializeOutputs(val)
+			if err != nil {
+				return err
+			}
+
+			out, ok = outs[idx]
+
+			return nil
+		})
+	})
+	if err != nil {
+		return transaction.Output{}, false, err
+	}
+
+	return out, ok, nil
+}
+
+// Balance sums every unspent output locked to the given public key hash.
+func (c *Chain) Balance(pubKeyHash []byte) (int, error) {
+	outs, err := c.FindUTXO(pubKeyHash)
+	if err != nil {
+		return 0, err
+	}
+
+	balance := 0
+	for _, out := range outs {
+		balance += out.Value
+	}
+
+	return balance, nil
+}
+
+// Reindex drops the UTXO index and rebuilds it walking the whole chain.
+func (c *Chain) Reindex() error {
+	if err := c.deleteByPrefix(utxoPrefix); err != nil {
+		return err
+	}
+
+	unspent := make(map[string]UnspentOutputs)
+	spent := make(map[string]map[int]bool)
+
+	it := c.Iterator()
+	for {
+		b, err := it.Next()
+		if err != nil {
+			return err
+		}
+
+		if b == nil {
+			break
+		}
+
+		// Walk the transactions backwards so spends inside the same block
+		// are seen before the outputs they consume
+		for i := len(b.Transactions) - 1; i >= 0; i-- {
+			tx := b.Transactions[i]
+			id := string(tx.ID)
+
+			for idx, out := range tx.Outputs {
+				if spent[id][idx] {
+					continue
+				}
+
+				if unspent[id] == nil {
+					unspent[id] = make(UnspentOutputs)
+				}
+				unspent[id][idx] = out
+			}
+
+			if tx.IsCoinBase() {
+				continue
+			}
+
+			for _, in := range tx.Inputs {
+				inID := string(in.ID)
+				if spent[inID] == nil {
+					spent[inID] = make(map[int]bool)
+				}
+				spent[inID][in.Out] = true
+			}
+		}
+	}
+
+	batch := c.Database.NewWriteBatch()
+	defer batch.Cancel()
+
+	for id, outs := range unspent {
+		data, err := outs.Serialize()
+		if err != nil {
+			return err
+		}
+
+		err = batch.Set(utxoKey([]byte(id)), data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return batch.Flush()
+}
+
+// forEachUTXO calls fn for every transaction in the UTXO index until fn
+// returns false.
+func (c *Chain) forEachUTXO(fn func(txID []byte, outs UnspentOutputs) bool) error {
+	return c.Database.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Seek(utxoPrefix); it.ValidForPrefix(utxoPrefix); it.Next() {
+			item := it.Item()
+			txID := bytes.TrimPrefix(item.KeyCopy(nil), utxoPrefix)
+
+			var outs UnspentOutputs
+			err := item.Value(func(val []byte) error {
+				var err error
+				outs, err = DeserializeOutputs(val)
+
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			if !fn(txID, outs) {
+				break
+			}
+		}
+
+		return nil
+	})
+}
+
+func (c *Chain) deleteByPrefix(prefix []byte) error {
+	var keys [][]byte
+
+	err := c.Database.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			keys = append(keys, it.Item().KeyCopy(nil))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	batch := c.Database.NewWriteBatch()
+	defer batch.Cancel()
+
+	for _, key := range keys {
+		if err := batch.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	return batch.Flush()
+}
+
+func utxoKey(txID []byte) []byte {
+	return bytes.Join([][]byte{utxoPrefix, txID}, []byte{})
+}
@@ -0,0 +1,144 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+)
+
+var (
+	ErrInvalidProof    = errors.New("invalid proof of work")
+	ErrHashMismatch    = errors.New("block hash does not match its content")
+	ErrBrokenLink      = errors.New("block does not link to its parent")
+	ErrInvalidCoinbase = errors.New("invalid coinbase transaction")
+)
+
+// VerifyError reports the first block that failed the chain verification.
+type VerifyError struct {
+	Hash []byte
+	// Height is -1 when the failure happened before the height was known
+	Height int
+	Err    error
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("block %x at height %d: %s", e.Hash, e.Height, e.Err)
+}
+
+func (e *VerifyError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes every VerifyError match ErrInvalidBlock.
+func (e *VerifyError) Is(target error) bool {
+	return target == ErrInvalidBlock
+}
+
+// TransactionError reports a transaction breaking a rule of the transaction
+// package. It matches ErrInvalidTransaction, or ErrInvalidCoinbase for
+// coinbase transactions, along with the error it wraps.
+type TransactionError struct {
+	ID       []byte
+	Coinbase bool
+	Err      error
+}
+
+func (e *TransactionError) Error() string {
+	if e.Coinbase {
+		return fmt.Sprintf("%s: %x: %s", ErrInvalidCoinbase, e.ID, e.Err)
+	}
+
+	return fmt.Sprintf("%s: %x: %s", ErrInvalidTransaction, e.ID, e.Err)
+}
+
+func (e *TransactionError) Unwrap() error {
+	return e.Err
+}
+
+func (e *TransactionError) Is(target error) bool {
+	if e.Coinbase {
+		return target == ErrInvalidCoinbase
+	}
+
+	return target == ErrInvalidTransaction
+}
+
+// Verify walks the whole chain checking the header, proof of work, hash and
+// parent link of every block, then replays the transactions from the genesis block
+// checking that every one of them spends existing unspent outputs with valid
+// signatures.
+func (c *Chain) Verify(ctx context.Context) error {
+	var blocks []*block.Block
+
+	it := c.Iterator()
+	expectedHash := c.LastHash()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// The height is only known from the child of the expected block
+		height := -1
+		if len(blocks) > 0 {
+			height = int(blocks[len(blocks)-1].Height) - 1
+		}
+
+		b, err := it.Next()
+		if err != nil {
+			return &VerifyError{Hash: expectedHash, Height: height, Err: err}
+		}
+
+		if b == nil {
+			break
+		}
+
+		// Blocks are stored by hash, a different hash means the parent
+		// pointer of the child does not match
+		if !bytes.Equal(b.Hash, expectedHash) {
+			return &VerifyError{Hash: expectedHash, Height: height, Err: ErrBrokenLink}
+		}
+
+		blocks = append(blocks, b)
+		expectedHash = b.PrevHash
+	}
+
+	utxo := make(memoryView)
+	var timestamps []int64
+	var prev *block.Block
+	now := time.Now()
+
+	for height := 0; height < len(blocks); height++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		b := blocks[len(blocks)-1-height]
+
+		bits, err := c.NextBits(prev)
+		if err != nil {
+			return &VerifyError{Hash: b.Hash, Height: height, Err: err}
+		}
+
+		if err := validateHeader(b, prev, bits, timestamps, now); err != nil {
+			return &VerifyError{Hash: b.Hash, Height: height, Err: err}
+		}
+
+		if err := verifyTransactions(b, utxo); err != nil {
+			return &VerifyError{Hash: b.Hash, Height: height, Err: err}
+		}
+
+		timestamps = append(timestamps, b.Timestamp)
+		if len(timestamps) > MedianTimeBlocks {
+			timestamps = timestamps[1:]
+		}
+
+		prev = b
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dgraph-io/badger"
+)
+
+func TestVerifyReportsUnreadableBlocks(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage func(txn *badger.Txn, hash []byte) error
+	}{
+		{
+			name: "missing block",
+			damage: func(txn *badger.Txn, hash []byte) error {
+				return txn.Delete(blockKey(hash))
+			},
+		},
+		{
+			name: "corrupt block",
+			damage: func(txn *badger.Txn, hash []byte) error {
+				return txn.Set(blockKey(hash), []byte("corrupt"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestChain(t)
+			miner := newWallets(t, 1)[0]
+
+			branch, err := mineBranch(t, c, genesisBlock(t, c), 3, 10, miner)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			damaged := branch[1]
+
+			err = c.Database.Update(func(txn *badger.Txn) error {
+				return tt.damage(txn, damaged.Hash)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = c.Verify(context.Background())
+
+			var verifyErr *VerifyError
+			if !errors.As(err, &verifyErr) || !errors.Is(err, ErrInvalidBlock) {
+				t.Fatalf("Verify() error = %v, want a %T", err, verifyErr)
+			}
+
+			if !bytes.Equal(verifyErr.Hash, damaged.Hash) || verifyErr.Height != int(damaged.Height) {
+				t.Fatalf("Verify() reported block %x at height %d, want %x at height %d", verifyErr.Hash, verifyErr.Height, damaged.Hash, damaged.Height)
+			}
+		})
+	}
+}
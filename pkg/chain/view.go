@@ -0,0 +1,251 @@
+package chain
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/dgraph-io/badger"
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+)
+
+// utxoView is the set of unspent outputs the transactions of a block are
+// validated and applied against.
+type utxoView interface {
+	// spend removes the output at idx of the transaction with the given ID,
+	// reporting false when it does not exist or was already spent
+	spend(txID []byte, idx int) (transaction.Output, bool, error)
+	// add adds every output of tx
+	add(tx *transaction.Transaction) error
+}
+
+// memoryView keeps the unspent outputs in memory, keyed by outpointKey.
+type memoryView map[string]transaction.Output
+
+func (v memoryView) spend(txID []byte, idx int) (transaction.Output, bool, error) {
+	key := outpointKey(txID, idx)
+
+	out, ok := v[key]
+	delete(v, key)
+
+	return out, ok, nil
+}
+
+func (v memoryView) add(tx *transaction.Transaction) error {
+	for idx, out := range tx.Outputs {
+		v[outpointKey(tx.ID, idx)] = out
+	}
+
+	return nil
+}
+
+// spentOutput is an output spent by a block, kept to restore it when the
+// block is disconnected.
+type spentOutput struct {
+	TxID   []byte
+	Index  int
+	Output transaction.Output
+}
+
+// txnView applies the changes to the UTXO index inside a database
+// transaction, recording the spent outputs as undo data.
+type txnView struct {
+	txn  *badger.Txn
+	undo []spentOutput
+}
+
+func (v *txnView) spend(txID []byte, idx int) (transaction.Output, bool, error) {
+	outs, err := getOutputs(v.txn, txID)
+	if err != nil {
+		return transaction.Output{}, false, err
+	}
+
+	out, ok := outs[idx]
+	if !ok {
+		return transaction.Output{}, false, nil
+	}
+
+	delete(outs, idx)
+
+	if err := setOutputs(v.txn, txID, outs); err != nil {
+		return transaction.Output{}, false, err
+	}
+
+	v.undo = append(v.undo, spentOutput{TxID: txID, Index: idx, Output: out})
+
+	return out, true, nil
+}
+
+func (v *txnView) add(tx *transaction.Transaction) error {
+	outs := make(UnspentOutputs, len(tx.Outputs))
+	for idx, out := range tx.Outputs {
+		outs[idx] = out
+	}
+
+	return setOutputs(v.txn, tx.ID, outs)
+}
+
+// verifyTransactions validates the transactions of b against the view,
+// applying them as it goes: every input must spend an unspent output with a
+// valid signature, every output must have a positive value, no transaction
+// can create value and the coinbase can only claim the block reward plus the
+// fees.
+func verifyTransactions(b *block.Block, view utxoView) error {
+	if len(b.Transactions) == 0 || !b.Transactions[0].IsCoinBase() {
+		return fmt.Errorf("%w: first transaction must be the coinbase", ErrInvalidCoinbase)
+	}
+
+	fees := 0
+	coinbaseValue := 0
+
+	for idx, tx := range b.Transactions {
+		if !tx.ValidID() {
+			return fmt.Errorf("%w: %x: ID does not match its content", ErrInvalidTransaction, tx.ID)
+		}
+
+		outputValue, err := tx.CheckValues()
+		if err != nil {
+			return &TransactionError{ID: tx.ID, Coinbase: tx.IsCoinBase(), Err: err}
+		}
+
+		if tx.IsCoinBase() {
+			if idx != 0 {
+				return fmt.Errorf("%w: %x: unexpected coinbase", ErrInvalidCoinbase, tx.ID)
+			}
+
+			coinbaseValue = outputValue
+		} else {
+			// Only the spent outputs are needed to check the signatures
+			prevTxs := make(map[string]transaction.Transaction)
+			inputValue := 0
+
+			for _, in := range tx.Inputs {
+				out, ok, err := view.spend(in.ID, in.Out)
+				if err != nil {
+					return err
+				}
+
+				if !ok {
+					return fmt.Errorf("%w: %x: spends missing or spent output %s", ErrInvalidTransaction, tx.ID, outpointKey(in.ID, in.Out))
+				}
+
+				inputValue, err = transaction.AddValues(inputValue, out.Value)
+				if err != nil {
+					return &TransactionError{ID: tx.ID, Err: err}
+				}
+
+				id := hex.EncodeToString(in.ID)
+				prevTx := prevTxs[id]
+				prevTx.ID = in.ID
+				for len(prevTx.Outputs) <= in.Out {
+					prevTx.Outputs = append(prevTx.Outputs, transaction.Output{})
+				}
+				prevTx.Outputs[in.Out] = out
+				prevTxs[id] = prevTx
+			}
+
+			if !tx.Verify(prevTxs) {
+				return fmt.Errorf("%w: %x: invalid signature", ErrInvalidTransaction, tx.ID)
+			}
+
+			if outputValue > inputValue {
+				return fmt.Errorf("%w: %x: spends %d but only has %d", ErrInvalidTransaction, tx.ID, outputValue, inputValue)
+			}
+
+			fees, err = transaction.AddValues(fees, inputValue-outputValue)
+			if err != nil {
+				return &TransactionError{ID: tx.ID, Err: err}
+			}
+		}
+
+		if err := view.add(tx); err != nil {
+			return err
+		}
+	}
+
+	allowed, err := transaction.AddValues(transaction.Reward, fees)
+	if err != nil {
+		return &TransactionError{ID: b.Transactions[0].ID, Coinbase: true, Err: err}
+	}
+
+	if coinbaseValue > allowed {
+		return fmt.Errorf("%w: pays %d, expected at most %d", ErrInvalidCoinbase, coinbaseValue, allowed)
+	}
+
+	return nil
+}
+
+// disconnectTransactions reverts the changes b made to the UTXO index,
+// removing the outputs it created and restoring the ones it spent from the
+// undo data.
+func disconnectTransactions(txn *badger.Txn, b *block.Block, undo []spentOutput) error {
+	for i := len(b.Transactions) - 1; i >= 0; i-- {
+		tx := b.Transactions[i]
+
+		if err := txn.Delete(utxoKey(tx.ID)); err != nil {
+			return err
+		}
+
+		if tx.IsCoinBase() {
+			continue
+		}
+
+		// The undo data is recorded in spending order
+		for j := len(tx.Inputs) - 1; j >= 0; j-- {
+			if len(undo) == 0 {
+				return fmt.Errorf("%w: block %x: undo data is incomplete", ErrCorruptData, b.Hash)
+			}
+
+			spent := undo[len(undo)-1]
+			undo = undo[:len(undo)-1]
+
+			outs, err := getOutputs(txn, spent.TxID)
+			if err != nil {
+				return err
+			}
+
+			outs[spent.Index] = spent.Output
+
+			if err := setOutputs(txn, spent.TxID, outs); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// getOutputs reads the unspent outputs of a transaction, which is empty when
+// all of them were spent.
+func getOutputs(txn *badger.Txn, txID []byte) (UnspentOutputs, error) {
+	item, err := txn.Get(utxoKey(txID))
+	if err == badger.ErrKeyNotFound {
+		return make(UnspentOutputs), nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var outs UnspentOutputs
+	err = item.Value(func(val []byte) error {
+		outs, err = DeserializeOutputs(val)
+
+		return err
+	})
+
+	return outs, err
+}
+
+func setOutputs(txn *badger.Txn, txID []byte, outs UnspentOutputs) error {
+	if len(outs) == 0 {
+		return txn.Delete(utxoKey(txID))
+	}
+
+	data, err := outs.Serialize()
+	if err != nil {
+		return err
+	}
+
+	return txn.Set(utxoKey(txID), data)
+}
@@ -2,15 +2,60 @@ package channel
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
+	"errors"
+	"log"
+	"sync"
 	"time"
 
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+	"github.com/herlon214/ipfs-blockchain/pkg/chain"
+	"github.com/herlon214/ipfs-blockchain/pkg/config"
+	"github.com/herlon214/ipfs-blockchain/pkg/wire"
+	"github.com/ipfs/go-cid"
 	"github.com/libp2p/go-libp2p-core/peer"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 )
 
-type downloadBlockFn func(ctx context.Context, fileCid string, filePath string) error
+const (
+	blockTopic = "blockchain:v1.0.0"
+
+	// Announced blocks are fetched at most blockFetchAttempts times, waiting
+	// blockFetchBackoff between the first attempts and doubling it each time
+	blockFetchAttempts = 4
+	blockFetchBackoff  = time.Second
+	// blockFetchTimeout bounds each attempt, so an announced CID nobody
+	// provides counts as a failed attempt
+	blockFetchTimeout = 30 * time.Second
+	// Announcements seen in this period are not fetched again
+	blockSeenTTL = 10 * time.Minute
+	// Each peer can send at most blockRateLimit messages every
+	// blockRateWindow
+	blockRateLimit  = 50
+	blockRateWindow = 10 * time.Second
+
+	// Peers are blacklisted once their misbehaviour score reaches banScore
+	banScore = 100
+	// invalidBlockPenalty is applied to peers announcing blocks that fail
+	// validation, fetchFailedPenalty to the ones whose announced block could
+	// not be fetched after every attempt
+	invalidBlockPenalty = 50
+	fetchFailedPenalty  = 10
+)
+
+// BlockFetcher fetches the blocks stored under the given CID.
+type BlockFetcher interface {
+	// GetBlock fetches the block with the given hash and the CID of its
+	// parent
+	GetBlock(ctx context.Context, c cid.Cid, hash []byte) (*block.Block, cid.Cid, error)
+	// SetBlockCID records the CID of a fetched block once it is connected
+	SetBlockCID(hash []byte, c cid.Cid)
+}
+
+// Chain is the view of the blockchain the channel hands fetched blocks to.
+type Chain interface {
+	HasBlock(hash []byte) bool
+	ConnectBlock(b *block.Block) error
+}
 
 type BlockChannel struct {
 	ctx   context.Context
@@ -18,64 +63,95 @@ type BlockChannel struct {
 	topic *pubsub.Topic
 	sub   *pubsub.Subscription
 
-	selfID          peer.ID
-	downloadBlockFn downloadBlockFn
-}
+	selfID    peer.ID
+	networkID string
+	fetcher   BlockFetcher
+	chain     Chain
+	seen      *seenCache
+	limiter   *peerLimiter
+	scores    *peerScores
 
-type Blocks struct {
-	Items map[string]string `json:"items"`
+	fetchingMx sync.Mutex
+	// fetching holds the CIDs of the announced blocks being fetched
+	fetching map[string]bool
+
+	// OnTip, when set, is called with the tips advertised by other peers
+	// and with announced blocks not building on the local tip, so the
+	// missing blocks can be synchronized
+	OnTip func(from peer.ID, hash []byte, height uint64, cid string)
 }
 
-func NewBlockChannel(ctx context.Context, ps *pubsub.PubSub, selfID peer.ID) (*BlockChannel, error) {
-	topic, err := ps.Join("blockchain:v1.0.0")
+func NewBlockChannel(ctx context.Context, ps *pubsub.PubSub, selfID peer.ID, cfg *config.Config, fetcher BlockFetcher, c Chain) (*BlockChannel, error) {
+	bc := &BlockChannel{
+		ctx:       ctx,
+		ps:        ps,
+		selfID:    selfID,
+		networkID: cfg.Network,
+		fetcher:   fetcher,
+		chain:     c,
+		seen:      newSeenCache(blockSeenTTL),
+		limiter:   newPeerLimiter(blockRateLimit, blockRateWindow),
+		scores:    newPeerScores(banScore),
+		fetching:  make(map[string]bool),
+	}
+
+	// Messages rejected or ignored by the validator are not relayed to other
+	// peers nor delivered to the subscription
+	err := ps.RegisterTopicValidator(blockTopic, bc.validate)
 	if err != nil {
 		return nil, err
 	}
 
-	sub, err := topic.Subscribe()
+	topic, err := ps.Join(blockTopic)
 	if err != nil {
 		return nil, err
 	}
 
-	bc := &BlockChannel{
-		ctx:    ctx,
-		topic:  topic,
-		sub:    sub,
-		ps:     ps,
-		selfID: selfID,
+	sub, err := topic.Subscribe()
+	if err != nil {
+		return nil, err
 	}
 
+	bc.topic = topic
+	bc.sub = sub
+
+	go bc.prune()
 	go bc.ReadBlocks()
 
 	return bc, nil
-
 }
 
-func (bc *BlockChannel) BroadcastBlocks(blocks map[string]string) error {
-	data, err := json.Marshal(Blocks{Items: blocks})
-	if err != nil {
-		panic(err)
-	}
-
-	return bc.topic.Publish(bc.ctx, data)
+// AnnounceBlock tells the other peers the block is available under the
+// given CID.
+func (bc *BlockChannel) AnnounceBlock(b *block.Block, cid string) error {
+	return bc.publish(&wire.AnnounceBlock{
+		Hash:   b.Hash,
+		Height: b.Height,
+		CID:    cid,
+	})
 }
 
-func (bc *BlockChannel) ReadBlocks() {
-	fmt.Println("Waiting for blocks...")
+// BroadcastStatus advertises the local chain tip.
+func (bc *BlockChannel) BroadcastStatus(tip *block.Block, cid string) error {
+	return bc.publish(&wire.Status{
+		Height:  tip.Height,
+		TipHash: tip.Hash,
+		TipCID:  cid,
+	})
+}
 
-	go func() {
-		for {
-			time.Sleep(time.Second)
-			peerList := bc.topic.ListPeers()
+// Peers returns the peers subscribed to the block topic.
+func (bc *BlockChannel) Peers() []peer.ID {
+	return bc.topic.ListPeers()
+}
 
-			fmt.Println("Found", len(peerList), "peers")
+func (bc *BlockChannel) publish(msg wire.Message) error {
+	return bc.topic.Publish(bc.ctx, wire.Encode(bc.networkID, msg))
+}
 
-			for _, peer := range peerList {
-				fmt.Println(peer.Pretty())
-			}
-		}
+func (bc *BlockChannel) ReadBlocks() {
+	log.Println("Waiting for blocks...")
 
-	}()
 	for {
 		msg, err := bc.sub.Next(bc.ctx)
 		if err != nil {
@@ -83,21 +159,213 @@ func (bc *BlockChannel) ReadBlocks() {
 		}
 
 		if msg.ReceivedFrom == bc.selfID {
-			fmt.Println("Self message", msg.ReceivedFrom.Pretty())
 			continue
 		}
 
-		fmt.Println(msg.ReceivedFrom)
+		// The message was decoded by the validator. It is handled on
+		// behalf of the peer that published it, the one it was received
+		// from only relayed it
+		from := msg.GetFrom()
 
-		var blocksMsg Blocks
-		err = json.Unmarshal(msg.Data, &blocksMsg)
-		if err != nil {
-			continue
+		switch m := msg.ValidatorData.(type) {
+		case *wire.AnnounceBlock:
+			log.Printf("Block %x at height %d announced with CID %s\n", m.Hash, m.Height, m.CID)
+
+			if bc.chain.HasBlock(m.Hash) || bc.seen.Has(m.CID) || !bc.startFetch(m.CID) {
+				continue
+			}
+
+			go bc.handleAnnouncement(from, m)
+		case *wire.Status:
+			log.Printf("Peer %s tip %x at height %d\n", from.Pretty(), m.TipHash, m.Height)
+
+			if bc.OnTip != nil && !bc.chain.HasBlock(m.TipHash) {
+				bc.OnTip(from, m.TipHash, m.Height, m.TipCID)
+			}
+		}
+	}
+}
+
+func (bc *BlockChannel) validate(ctx context.Context, from peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+	// Messages published by this node are built from the local chain
+	if from == bc.selfID {
+		return pubsub.ValidationAccept
+	}
+
+	if !bc.limiter.Allow(from) {
+		return pubsub.ValidationIgnore
+	}
+
+	env, payload, err := wire.Decode(bc.networkID, msg.Data)
+	if err != nil {
+		// Peers on other networks or versions are not misbehaving
+		if errors.Is(err, wire.ErrWrongNetwork) || errors.Is(err, wire.ErrIncompatibleVersion) {
+			log.Printf("Ignoring message from %s: %s\n", from.Pretty(), err)
+
+			return pubsub.ValidationIgnore
 		}
 
-		for cid, filepath := range blocksMsg.Items {
-			// bc.downloadBlockFn(bc.ctx, cid, filepath)
-			fmt.Println(cid, filepath)
+		return pubsub.ValidationReject
+	}
+
+	switch m := payload.(type) {
+	case *wire.AnnounceBlock:
+		if len(m.Hash) == 0 || !validCID(m.CID) {
+			return pubsub.ValidationReject
+		}
+	case *wire.Status:
+		if len(m.TipHash) == 0 || !validCID(m.TipCID) {
+			return pubsub.ValidationReject
 		}
+	default:
+		log.Printf("Rejecting %s message from %s\n", env.Type, from.Pretty())
+
+		return pubsub.ValidationReject
+	}
+
+	msg.ValidatorData = payload
+
+	return pubsub.ValidationAccept
+}
+
+func validCID(s string) bool {
+	_, err := cid.Decode(s)
+
+	return err == nil
+}
+
+// startFetch records the CID as being fetched, returning false when it
+// already is.
+func (bc *BlockChannel) startFetch(c string) bool {
+	bc.fetchingMx.Lock()
+	defer bc.fetchingMx.Unlock()
+
+	if bc.fetching[c] {
+		return false
+	}
+
+	bc.fetching[c] = true
+
+	return true
+}
+
+func (bc *BlockChannel) doneFetch(c string) {
+	bc.fetchingMx.Lock()
+	defer bc.fetchingMx.Unlock()
+
+	delete(bc.fetching, c)
+}
+
+// handleAnnouncement fetches the announced block and connects it to the
+// chain, penalising the announcing peer when the block cannot be fetched or
+// is invalid.
+func (bc *BlockChannel) handleAnnouncement(from peer.ID, m *wire.AnnounceBlock) {
+	defer bc.doneFetch(m.CID)
+
+	// The validator only accepts valid CIDs
+	c, err := cid.Decode(m.CID)
+	if err != nil {
+		return
+	}
+
+	b, err := bc.fetchBlock(m, c)
+	if err != nil {
+		if bc.ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("Failed to fetch block %x from %s: %s\n", m.Hash, from.Pretty(), err)
+		bc.penalise(from, fetchFailedPenalty)
+
+		return
+	}
+
+	// Only blocks actually fetched are marked as seen, a bogus announcement
+	// must not stop the block being fetched when announced again
+	bc.seen.Add(m.CID)
+
+	err = bc.chain.ConnectBlock(b)
+	if err != nil {
+		log.Printf("Rejected block %x from %s: %s\n", m.Hash, from.Pretty(), err)
+
+		switch {
+		case errors.Is(err, chain.ErrInvalidBlock):
+			bc.penalise(from, invalidBlockPenalty)
+		case errors.Is(err, chain.ErrBrokenLink):
+			// Blocks not building on a known block may be valid, they are
+			// just not connectable until their parents are synced
+			if bc.OnTip != nil {
+				bc.OnTip(from, m.Hash, m.Height, m.CID)
+			}
+		}
+
+		return
+	}
+
+	bc.fetcher.SetBlockCID(b.Hash, c)
+
+	log.Printf("Connected block %x at height %d\n", b.Hash, b.Height)
+}
+
+// fetchBlock downloads and decodes the announced block, retrying with an
+// exponential backoff.
+func (bc *BlockChannel) fetchBlock(m *wire.AnnounceBlock, c cid.Cid) (*block.Block, error) {
+	backoff := blockFetchBackoff
+
+	var err error
+	for attempt := 1; attempt <= blockFetchAttempts; attempt++ {
+		var b *block.Block
+
+		b, err = bc.downloadBlock(m, c)
+		if err == nil {
+			return b, nil
+		}
+
+		if attempt == blockFetchAttempts {
+			break
+		}
+
+		log.Printf("Fetching block %x failed (attempt %d/%d), retrying in %s: %s\n", m.Hash, attempt, blockFetchAttempts, backoff, err)
+
+		select {
+		case <-bc.ctx.Done():
+			return nil, bc.ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+
+	return nil, err
+}
+
+func (bc *BlockChannel) downloadBlock(m *wire.AnnounceBlock, c cid.Cid) (*block.Block, error) {
+	ctx, cancel := context.WithTimeout(bc.ctx, blockFetchTimeout)
+	defer cancel()
+
+	b, _, err := bc.fetcher.GetBlock(ctx, c, m.Hash)
+
+	return b, err
+}
+
+func (bc *BlockChannel) prune() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-bc.ctx.Done():
+			return
+		case <-ticker.C:
+			bc.seen.Prune()
+			bc.limiter.Prune()
+		}
+	}
+}
+
+func (bc *BlockChannel) penalise(id peer.ID, penalty int) {
+	if bc.scores.Penalise(id, penalty) {
+		log.Printf("Blacklisting peer %s with score %d\n", id.Pretty(), bc.scores.Score(id))
+		bc.ps.BlacklistPeer(id)
 	}
 }
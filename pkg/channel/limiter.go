@@ -0,0 +1,111 @@
+package channel
+
+import (
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// seenCache remembers message IDs for a while to drop duplicates.
+type seenCache struct {
+	mx    sync.Mutex
+	ttl   time.Duration
+	items map[string]time.Time
+}
+
+func newSeenCache(ttl time.Duration) *seenCache {
+	return &seenCache{
+		ttl:   ttl,
+		items: make(map[string]time.Time),
+	}
+}
+
+// Has reports whether the ID was seen within the cache TTL.
+func (s *seenCache) Has(id string) bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	seenAt, ok := s.items[id]
+
+	return ok && time.Since(seenAt) < s.ttl
+}
+
+// Add records the ID, returning false when it was already seen.
+func (s *seenCache) Add(id string) bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	now := time.Now()
+	if seenAt, ok := s.items[id]; ok && now.Sub(seenAt) < s.ttl {
+		return false
+	}
+
+	s.items[id] = now
+
+	return true
+}
+
+// Prune drops the IDs older than the cache TTL.
+func (s *seenCache) Prune() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	now := time.Now()
+	for id, seenAt := range s.items {
+		if now.Sub(seenAt) >= s.ttl {
+			delete(s.items, id)
+		}
+	}
+}
+
+type peerWindow struct {
+	start time.Time
+	count int
+}
+
+// peerLimiter allows at most limit messages per peer on every window.
+type peerLimiter struct {
+	mx      sync.Mutex
+	limit   int
+	window  time.Duration
+	windows map[peer.ID]*peerWindow
+}
+
+func newPeerLimiter(limit int, window time.Duration) *peerLimiter {
+	return &peerLimiter{
+		limit:   limit,
+		window:  window,
+		windows: make(map[peer.ID]*peerWindow),
+	}
+}
+
+func (l *peerLimiter) Allow(id peer.ID) bool {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	now := time.Now()
+
+	w, ok := l.windows[id]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &peerWindow{start: now}
+		l.windows[id] = w
+	}
+
+	w.count++
+
+	return w.count <= l.limit
+}
+
+// Prune drops the windows that already expired.
+func (l *peerLimiter) Prune() {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	now := time.Now()
+	for id, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, id)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package channel
+
+import (
+	"sync"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// peerScores tracks the misbehaviour of each peer. Peers reaching the ban
+// threshold are reported once by Penalise.
+type peerScores struct {
+	mx        sync.Mutex
+	threshold int
+	scores    map[peer.ID]int
+}
+
+func newPeerScores(threshold int) *peerScores {
+	return &peerScores{
+		threshold: threshold,
+		scores:    make(map[peer.ID]int),
+	}
+}
+
+// Penalise adds the penalty to the peer score, returning true when the score
+// just reached the ban threshold.
+func (s *peerScores) Penalise(id peer.ID, penalty int) bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	before := s.scores[id]
+	s.scores[id] = before + penalty
+
+	return before < s.threshold && s.scores[id] >= s.threshold
+}
+
+func (s *peerScores) Score(id peer.ID) int {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return s.scores[id]
+}
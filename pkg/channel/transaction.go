@@ -0,0 +1,180 @@
+package channel
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/config"
+	"github.com/herlon214/ipfs-blockchain/pkg/mempool"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+	"github.com/herlon214/ipfs-blockchain/pkg/wire"
+	"github.com/libp2p/go-libp2p-core/peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+)
+
+const (
+	txTopic = "blockchain-tx:v1.0.0"
+
+	// Transactions seen in this period are not validated nor relayed again
+	txSeenTTL = 10 * time.Minute
+	// Each peer can send at most txRateLimit transactions every txRateWindow
+	txRateLimit  = 100
+	txRateWindow = 10 * time.Second
+)
+
+type TxChannel struct {
+	ctx   context.Context
+	ps    *pubsub.PubSub
+	topic *pubsub.Topic
+	sub   *pubsub.Subscription
+
+	selfID    peer.ID
+	networkID string
+	pool      *mempool.Pool
+	seen      *seenCache
+	limiter   *peerLimiter
+}
+
+func NewTxChannel(ctx context.Context, ps *pubsub.PubSub, selfID peer.ID, cfg *config.Config, pool *mempool.Pool) (*TxChannel, error) {
+	tc := &TxChannel{
+		ctx:       ctx,
+		ps:        ps,
+		selfID:    selfID,
+		networkID: cfg.Network,
+		pool:      pool,
+		seen:      newSeenCache(txSeenTTL),
+		limiter:   newPeerLimiter(txRateLimit, txRateWindow),
+	}
+
+	// Messages rejected or ignored by the validator are not relayed to other
+	// peers nor delivered to the subscription
+	err := ps.RegisterTopicValidator(txTopic, tc.validate)
+	if err != nil {
+		return nil, err
+	}
+
+	topic, err := ps.Join(txTopic)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := topic.Subscribe()
+	if err != nil {
+		return nil, err
+	}
+
+	tc.topic = topic
+	tc.sub = sub
+
+	go tc.prune()
+	go tc.ReadTransactions()
+
+	return tc, nil
+}
+
+// SubmitTransaction admits a new transaction to the mempool and broadcasts it
+// to the other peers.
+func (tc *TxChannel) SubmitTransaction(tx *transaction.Transaction) error {
+	if err := tc.pool.Add(tx); err != nil {
+		return err
+	}
+
+	tc.seen.Add(hex.EncodeToString(tx.ID))
+
+	return tc.BroadcastTransaction(tx)
+}
+
+func (tc *TxChannel) BroadcastTransaction(tx *transaction.Transaction) error {
+	return tc.topic.Publish(tc.ctx, wire.Encode(tc.networkID, &wire.AnnounceTx{Tx: tx}))
+}
+
+func (tc *TxChannel) ReadTransactions() {
+	for {
+		msg, err := tc.sub.Next(tc.ctx)
+		if err != nil {
+			return
+		}
+
+		if msg.ReceivedFrom == tc.selfID {
+			continue
+		}
+
+		// The validator already admitted the transaction to the mempool
+		if tx, ok := msg.ValidatorData.(*transaction.Transaction); ok {
+			log.Printf("Received transaction %x from %s\n", tx.ID, msg.ReceivedFrom.Pretty())
+		}
+	}
+}
+
+func (tc *TxChannel) validate(ctx context.Context, from peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+	// Transactions published by this node were validated on submission
+	if from == tc.selfID {
+		return pubsub.ValidationAccept
+	}
+
+	if !tc.limiter.Allow(from) {
+		return pubsub.ValidationIgnore
+	}
+
+	_, payload, err := wire.Decode(tc.networkID, msg.Data)
+	if err != nil {
+		// Peers on other networks or versions are not misbehaving
+		if errors.Is(err, wire.ErrWrongNetwork) || errors.Is(err, wire.ErrIncompatibleVersion) {
+			log.Printf("Ignoring transaction from %s: %s\n", from.Pretty(), err)
+
+			return pubsub.ValidationIgnore
+		}
+
+		return pubsub.ValidationReject
+	}
+
+	announce, ok := payload.(*wire.AnnounceTx)
+	if !ok {
+		return pubsub.ValidationReject
+	}
+
+	tx := announce.Tx
+	id := hex.EncodeToString(tx.ID)
+
+	if tc.seen.Has(id) {
+		return pubsub.ValidationIgnore
+	}
+
+	err = tc.pool.Add(tx)
+	if err != nil {
+		if errors.Is(err, mempool.ErrInvalidTransaction) {
+			return pubsub.ValidationReject
+		}
+
+		// Duplicates, a full pool and double spends, which can be an honest
+		// race with a block not received yet, are not relayed but the peer
+		// is not penalised
+		return pubsub.ValidationIgnore
+	}
+
+	// Only admitted transactions are marked as seen, an invalid copy must
+	// not shadow the valid one
+	tc.seen.Add(id)
+
+	msg.ValidatorData = tx
+
+	return pubsub.ValidationAccept
+}
+
+func (tc *TxChannel) prune() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-tc.ctx.Done():
+			return
+		case <-ticker.C:
+			tc.seen.Prune()
+			tc.limiter.Prune()
+		}
+	}
+}
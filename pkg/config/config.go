@@ -0,0 +1,280 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/params"
+	"github.com/herlon214/ipfs-blockchain/pkg/wallets"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// FileName is the configuration file looked up inside the data
+	// directory when no file is given
+	FileName = "config.yaml"
+	// EnvPrefix prefixes the environment variables overriding the flags,
+	// like BLOCKCHAIN_DATADIR for --datadir
+	EnvPrefix = "BLOCKCHAIN_"
+)
+
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+type Config struct {
+	// DataDir holds the chain database and the IPFS repository
+	DataDir string `yaml:"dataDir"`
+	// Network selects the consensus parameters, see params.Networks
+	Network string `yaml:"network"`
+	// KeyFile is the private key identifying the node, relative to DataDir
+	KeyFile string `yaml:"keyFile"`
+
+	ListenAddrs     []string `yaml:"listenAddrs"`
+	BootstrapPeers  []string `yaml:"bootstrapPeers"`
+	IPFSListenAddrs []string `yaml:"ipfsListenAddrs"`
+
+	Genesis Genesis `yaml:"genesis"`
+	Mining  Mining  `yaml:"mining"`
+	API     API     `yaml:"api"`
+}
+
+// Genesis overrides the genesis block of the network, to start a private
+// chain. Fields left empty keep the value of the network, see
+// params.Genesis.
+type Genesis struct {
+	// Address receives the genesis reward
+	Address string `yaml:"address"`
+	// Timestamp is the unix time of the block
+	Timestamp int64 `yaml:"timestamp"`
+	// Message is the coinbase data
+	Message string `yaml:"message"`
+}
+
+type Mining struct {
+	Enabled bool `yaml:"enabled"`
+	// Address receives the block rewards and fees
+	Address string `yaml:"address"`
+	// Workers is the number of goroutines mining, zero uses every CPU
+	Workers int `yaml:"workers"`
+}
+
+// API configures the HTTP JSON API of the node.
+type API struct {
+	// Listen is the address the API listens on, empty disables it
+	Listen string `yaml:"listen"`
+	// Token, when set, must be sent by the clients as a bearer token
+	Token string `yaml:"token"`
+}
+
+// Default returns the configuration used when nothing is set, keeping the
+// data in the working directory.
+func Default() *Config {
+	return &Config{
+		DataDir:         ".",
+		Network:         params.Main.Name,
+		KeyFile:         "node.key",
+		ListenAddrs:     []string{"/ip4/0.0.0.0/tcp/4005"},
+		IPFSListenAddrs: []string{"/ip4/0.0.0.0/tcp/4001", "/ip6/::/tcp/4001"},
+		API: API{
+			Listen: "127.0.0.1:4080",
+		},
+	}
+}
+
+// AddFlags registers the flags overriding the configuration file.
+func AddFlags(flags *pflag.FlagSet) {
+	d := Default()
+
+	flags.String("config", "", fmt.Sprintf("configuration file (default <datadir>/%s)", FileName))
+	flags.String("datadir", d.DataDir, "directory holding the chain and the IPFS repository")
+	flags.String("network", d.Network, "network name")
+	flags.String("key", d.KeyFile, "node private key file, relative to the data directory")
+	flags.StringSlice("listen", d.ListenAddrs, "multiaddrs to listen on")
+	flags.StringSlice("bootstrap", nil, "multiaddrs of the peers to connect to on start")
+	flags.Bool("mine", false, "mine blocks")
+	flags.String("mining-address", "", "address receiving the mining rewards")
+	flags.Int("mining-workers", 0, "mining goroutines, defaults to the number of CPUs")
+	flags.String("api-listen", d.API.Listen, "address of the HTTP API, empty disables it")
+	flags.String("api-token", "", "bearer token required by the HTTP API")
+}
+
+// Load builds the configuration from the defaults, the configuration file,
+// the environment variables and the flags, each one overriding the previous.
+// The flags must have been registered with AddFlags.
+func Load(flags *pflag.FlagSet) (*Config, error) {
+	if err := applyEnv(flags); err != nil {
+		return nil, err
+	}
+
+	c := Default()
+
+	if flags.Changed("datadir") {
+		c.DataDir, _ = flags.GetString("datadir")
+	}
+
+	path, _ := flags.GetString("config")
+	required := path != ""
+	if !required {
+		path = filepath.Join(c.DataDir, FileName)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && (required || !os.IsNotExist(err)) {
+		return nil, err
+	}
+
+	if err == nil {
+		if err := yaml.UnmarshalStrict(data, c); err != nil {
+			return nil, fmt.Errorf("%w: %s: %s", ErrInvalidConfig, path, err)
+		}
+	}
+
+	if err := applyFlags(c, flags); err != nil {
+		return nil, err
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Validate checks the settings that can be checked without using them.
+func (c *Config) Validate() error {
+	if c.DataDir == "" {
+		return fmt.Errorf("%w: empty data directory", ErrInvalidConfig)
+	}
+
+	if _, ok := params.Networks[c.Network]; !ok {
+		return fmt.Errorf("%w: unknown network %q", ErrInvalidConfig, c.Network)
+	}
+
+	if c.Genesis.Timestamp < 0 {
+		return fmt.Errorf("%w: negative genesis timestamp", ErrInvalidConfig)
+	}
+
+	if c.Mining.Workers < 0 {
+		return fmt.Errorf("%w: negative mining workers", ErrInvalidConfig)
+	}
+
+	if c.Mining.Enabled && c.Mining.Address == "" {
+		return fmt.Errorf("%w: mining is enabled without a mining address", ErrInvalidConfig)
+	}
+
+	if c.Mining.Address != "" {
+		if _, err := wallets.DecodeAddress([]byte(c.Mining.Address)); err != nil {
+			return fmt.Errorf("%w: mining address %s: %s", ErrInvalidConfig, c.Mining.Address, err)
+		}
+	}
+
+	return nil
+}
+
+// Params returns the consensus parameters of the configured network.
+func (c *Config) Params() *params.Params {
+	return params.Networks[c.Network]
+}
+
+// GenesisParams returns the genesis block settings of the configured
+// network, overridden by the ones set in the configuration.
+func (c *Config) GenesisParams() params.Genesis {
+	g := c.Params().Genesis
+
+	if c.Genesis.Address != "" {
+		g.Address = c.Genesis.Address
+	}
+
+	if c.Genesis.Timestamp != 0 {
+		g.Timestamp = c.Genesis.Timestamp
+	}
+
+	if c.Genesis.Message != "" {
+		g.Message = c.Genesis.Message
+	}
+
+	return g
+}
+
+// ChainDir is the directory of the chain database.
+func (c *Config) ChainDir() string {
+	return filepath.Join(c.DataDir, "blocks")
+}
+
+// IPFSDir is the directory of the IPFS repository.
+func (c *Config) IPFSDir() string {
+	return filepath.Join(c.DataDir, "block-repo")
+}
+
+// KeyPath is the path of the node private key.
+func (c *Config) KeyPath() string {
+	if filepath.IsAbs(c.KeyFile) {
+		return c.KeyFile
+	}
+
+	return filepath.Join(c.DataDir, c.KeyFile)
+}
+
+// applyEnv sets the flags not given in the command line from their
+// environment variables.
+func applyEnv(flags *pflag.FlagSet) error {
+	var err error
+
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Changed || err != nil {
+			return
+		}
+
+		name := EnvPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+
+		if setErr := flags.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("%w: %s: %s", ErrInvalidConfig, name, setErr)
+		}
+	})
+
+	return err
+}
+
+// applyFlags overrides the configuration with the flags set.
+func applyFlags(c *Config, flags *pflag.FlagSet) error {
+	var err error
+
+	flags.Visit(func(f *pflag.Flag) {
+		if err != nil {
+			return
+		}
+
+		switch f.Name {
+		case "datadir":
+			c.DataDir, err = flags.GetString(f.Name)
+		case "network":
+			c.Network, err = flags.GetString(f.Name)
+		case "key":
+			c.KeyFile, err = flags.GetString(f.Name)
+		case "listen":
+			c.ListenAddrs, err = flags.GetStringSlice(f.Name)
+		case "bootstrap":
+			c.BootstrapPeers, err = flags.GetStringSlice(f.Name)
+		case "mine":
+			c.Mining.Enabled, err = flags.GetBool(f.Name)
+		case "mining-address":
+			c.Mining.Address, err = flags.GetString(f.Name)
+		case "mining-workers":
+			c.Mining.Workers, err = flags.GetInt(f.Name)
+		case "api-listen":
+			c.API.Listen, err = flags.GetString(f.Name)
+		case "api-token":
+			c.API.Token, err = flags.GetString(f.Name)
+		}
+	})
+
+	return err
+}
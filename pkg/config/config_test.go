@@ -0,0 +1,54 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/wallets"
+)
+
+func TestValidateMiningAddress(t *testing.T) {
+	w, err := wallets.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	address := string(w.Address())
+
+	// Changing the last character breaks the checksum
+	corrupted := address[:len(address)-1] + "2"
+	if address[len(address)-1] == '2' {
+		corrupted = address[:len(address)-1] + "3"
+	}
+
+	tests := []struct {
+		name    string
+		enabled bool
+		address string
+		valid   bool
+	}{
+		{"valid address", true, address, true},
+		{"mining disabled without address", false, "", true},
+		{"mining enabled without address", true, "", false},
+		{"checksum mismatch", true, corrupted, false},
+		{"not base58", false, "0OIl", false},
+		{"wrong length", true, "1111", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Default()
+			c.Mining.Enabled = tt.enabled
+			c.Mining.Address = tt.address
+
+			err := c.Validate()
+			if tt.valid && err != nil {
+				t.Fatalf("Validate() = %v", err)
+			}
+
+			if !tt.valid && !errors.Is(err, ErrInvalidConfig) {
+				t.Fatalf("Validate() error = %v, want %v", err, ErrInvalidConfig)
+			}
+		})
+	}
+}
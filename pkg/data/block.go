@@ -0,0 +1,203 @@
+package data
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	cbornode "github.com/ipfs/go-ipld-cbor"
+	"golang.org/x/net/context"
+)
+
+// storedPrefix is the datastore namespace of the CIDs of the blocks stored by
+// PutBlock
+var storedPrefix = datastore.NewKey("/blockchain/blocks")
+
+var (
+	ErrUnknownParent   = errors.New("parent block CID is unknown")
+	ErrInvalidNode     = errors.New("invalid block node")
+	ErrUnexpectedBlock = errors.New("block node does not hold the expected block")
+)
+
+// PutBlock stores the block and each of its transactions as DAG-CBOR nodes,
+// returning the CID of the block node. The parent block must have been
+// stored or fetched before, unless b is the genesis block.
+func (d *Data) PutBlock(ctx context.Context, b *block.Block) (cid.Cid, error) {
+	var prev *cid.Cid
+	if len(b.PrevHash) > 0 {
+		prevCid, ok := d.BlockCID(b.PrevHash)
+		if !ok {
+			return cid.Undef, fmt.Errorf("%w: %x", ErrUnknownParent, b.PrevHash)
+		}
+
+		prev = &prevCid
+	}
+
+	txs := make([]cid.Cid, 0, len(b.Transactions))
+	for _, tx := range b.Transactions {
+		n, err := wrapNode(newTxNode(tx))
+		if err != nil {
+			return cid.Undef, err
+		}
+
+		if err := d.ipfs.Dag().Add(ctx, n); err != nil {
+			return cid.Undef, err
+		}
+
+		txs = append(txs, n.Cid())
+	}
+
+	n, err := wrapNode(newBlockNode(b, prev, txs))
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	if err := d.ipfs.Dag().Add(ctx, n); err != nil {
+		return cid.Undef, err
+	}
+
+	if err := d.node.Repo.Datastore().Put(ctx, storedKey(b.Hash), n.Cid().Bytes()); err != nil {
+		return cid.Undef, err
+	}
+
+	d.SetBlockCID(b.Hash, n.Cid())
+
+	return n.Cid(), nil
+}
+
+// GetBlock fetches the block node with the given CID along with its
+// transactions, failing with ErrUnexpectedBlock unless the header hashes to
+// the expected hash. The parent node is fetched too, to recover the parent
+// hash, and its CID is returned along with the block, cid.Undef for the
+// genesis block.
+//
+// The CIDs of fetched blocks are not recorded, the caller hands them to
+// SetBlockCID once the block was validated and connected to the chain.
+func (d *Data) GetBlock(ctx context.Context, c cid.Cid, hash []byte) (*block.Block, cid.Cid, error) {
+	n, err := d.getBlockNode(ctx, c)
+	if err != nil {
+		return nil, cid.Undef, err
+	}
+
+	prevHash := []byte{}
+	prevCid := cid.Undef
+	if n.Prev != nil {
+		prev, err := d.getBlockNode(ctx, *n.Prev)
+		if err != nil {
+			return nil, cid.Undef, fmt.Errorf("parent of %s: %w", c, err)
+		}
+
+		prevHash = prev.Hash
+		prevCid = *n.Prev
+	}
+
+	txs := make([]*transaction.Transaction, 0, len(n.Txs))
+	for _, txCid := range n.Txs {
+		var txn txNode
+		if err := d.getNode(ctx, txCid, &txn); err != nil {
+			return nil, cid.Undef, fmt.Errorf("transaction %s: %w", txCid, err)
+		}
+
+		txs = append(txs, txn.transaction())
+	}
+
+	b := n.block(prevHash, txs)
+
+	// The parent hash is part of the header, a parent node lying about its
+	// hash fails this check too
+	headerHash := sha256.Sum256(b.Header.Bytes())
+	if !bytes.Equal(headerHash[:], hash) || !bytes.Equal(b.Hash, hash) {
+		return nil, cid.Undef, fmt.Errorf("%w: %s holds block %x, expected %x", ErrUnexpectedBlock, c, headerHash, hash)
+	}
+
+	return b, prevCid, nil
+}
+
+// BlockCID returns the CID of the block with the given hash, when it was
+// stored by this node or fetched and connected to the chain.
+func (d *Data) BlockCID(hash []byte) (cid.Cid, bool) {
+	d.mx.Lock()
+	c, ok := d.cids[hex.EncodeToString(hash)]
+	d.mx.Unlock()
+
+	if ok {
+		return c, true
+	}
+
+	c, ok = d.StoredCID(hash)
+	if ok {
+		d.SetBlockCID(hash, c)
+	}
+
+	return c, ok
+}
+
+// SetBlockCID records the CID of a block fetched by GetBlock, which must have
+// been validated and connected to the chain. The CID already known for the
+// block, if any, is kept.
+func (d *Data) SetBlockCID(hash []byte, c cid.Cid) {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	key := hex.EncodeToString(hash)
+	if _, ok := d.cids[key]; !ok {
+		d.cids[key] = c
+	}
+}
+
+// StoredCID returns the CID of the block with the given hash when it was
+// stored by PutBlock, possibly before the node restarted. Blocks only fetched
+// from other peers are left out, their content was not validated.
+func (d *Data) StoredCID(hash []byte) (cid.Cid, bool) {
+	data, err := d.node.Repo.Datastore().Get(context.Background(), storedKey(hash))
+	if err != nil {
+		return cid.Undef, false
+	}
+
+	c, err := cid.Cast(data)
+	if err != nil {
+		return cid.Undef, false
+	}
+
+	return c, true
+}
+
+func storedKey(hash []byte) datastore.Key {
+	return storedPrefix.ChildString(hex.EncodeToString(hash))
+}
+
+func (d *Data) getBlockNode(ctx context.Context, c cid.Cid) (*blockNode, error) {
+	var n blockNode
+	if err := d.getNode(ctx, c, &n); err != nil {
+		return nil, err
+	}
+
+	if n.Height > 0 && n.Prev == nil {
+		return nil, fmt.Errorf("%w: %s: missing parent link at height %d", ErrInvalidNode, c, n.Height)
+	}
+
+	return &n, nil
+}
+
+func (d *Data) getNode(ctx context.Context, c cid.Cid, v interface{}) error {
+	if c.Type() != cid.DagCBOR {
+		return fmt.Errorf("%w: %s is not a DAG-CBOR node", ErrInvalidNode, c)
+	}
+
+	n, err := d.ipfs.Dag().Get(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	if err := cbornode.DecodeInto(n.RawData(), v); err != nil {
+		return fmt.Errorf("%w: %s: %s", ErrInvalidNode, c, err)
+	}
+
+	return nil
+}
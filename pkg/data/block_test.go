@@ -0,0 +1,228 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+	"github.com/ipfs/go-cid"
+)
+
+var setupOnce sync.Once
+
+// openOffline opens an offline IPFS node on the repo in dir, creating it
+// when missing.
+func openOffline(t *testing.T, dir string) *Data {
+	t.Helper()
+
+	var err error
+	setupOnce.Do(func() {
+		err = setupPlugins("")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repoPath, err := createRepo(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node, err := createNode(context.Background(), repoPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := newData(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return d
+}
+
+func testTransaction(out int, values ...int) *transaction.Transaction {
+	tx := &transaction.Transaction{
+		Inputs: []transaction.Input{{ID: []byte("previous"), Out: out, Signature: []byte("signature"), PubKey: []byte("public key")}},
+	}
+
+	for _, value := range values {
+		tx.Outputs = append(tx.Outputs, transaction.Output{Value: value, PubKeyHash: []byte("hash")})
+	}
+
+	if err := tx.SetId(); err != nil {
+		panic(err)
+	}
+
+	return tx
+}
+
+func mine(t *testing.T, txs []*transaction.Transaction, prev *block.Block) *block.Block {
+	t.Helper()
+
+	var b *block.Block
+	if prev == nil {
+		b = block.New(txs, []byte{}, 0, 1)
+	} else {
+		b = block.New(txs, prev.Hash, prev.Height+1, 1)
+	}
+
+	if err := b.DeriveHash(); err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+func TestPutGetBlock(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "ipfs")
+	ctx := context.Background()
+
+	genesis := mine(t, []*transaction.Transaction{testTransaction(-1, 100)}, nil)
+	child := mine(t, []*transaction.Transaction{
+		testTransaction(-1, 110),
+		testTransaction(0, 60, 30),
+		testTransaction(1, 5),
+	}, genesis)
+
+	d := openOffline(t, dir)
+
+	if _, err := d.PutBlock(ctx, child); !errors.Is(err, ErrUnknownParent) {
+		t.Fatalf("PutBlock() before its parent error = %v, want %v", err, ErrUnknownParent)
+	}
+
+	genesisCid, err := d.PutBlock(ctx, genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	childCid, err := d.PutBlock(ctx, child)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restarted node still knows the CIDs of the blocks it stored
+	d = openOffline(t, dir)
+	defer d.Close()
+
+	for _, tt := range []struct {
+		b   *block.Block
+		cid string
+	}{
+		{genesis, genesisCid.String()},
+		{child, childCid.String()},
+	} {
+		stored, ok := d.StoredCID(tt.b.Hash)
+		if !ok || stored.String() != tt.cid {
+			t.Fatalf("StoredCID(%x) = %s, %v, want %s", tt.b.Hash, stored, ok, tt.cid)
+		}
+	}
+
+	got, parentCid, err := d.GetBlock(ctx, childCid, child.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parentCid != genesisCid {
+		t.Fatalf("parent CID = %s, want %s", parentCid, genesisCid)
+	}
+
+	if !reflect.DeepEqual(got, child) {
+		t.Fatalf("GetBlock() = %+v, want %+v", got, child)
+	}
+
+	if string(got.PrevHash) != string(genesis.Hash) {
+		t.Fatalf("parent hash = %x, want %x", got.PrevHash, genesis.Hash)
+	}
+
+	if string(got.MerkleRoot) != string(got.HashTransactions()) {
+		t.Fatal("merkle root does not match the fetched transactions")
+	}
+
+	if !block.NewProof(got).Validate() {
+		t.Fatal("fetched block does not validate")
+	}
+
+	hash := sha256.Sum256(block.NewProof(got).InitData(got.Nonce))
+	if string(hash[:]) != string(child.Hash) {
+		t.Fatalf("hash of the fetched header = %x, want %x", hash, child.Hash)
+	}
+
+	parent, parentCid, err := d.GetBlock(ctx, genesisCid, genesis.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parentCid.Defined() {
+		t.Fatalf("parent CID of the genesis block = %s", parentCid)
+	}
+
+	if !reflect.DeepEqual(parent, genesis) {
+		t.Fatalf("GetBlock() = %+v, want %+v", parent, genesis)
+	}
+}
+
+func TestGetBlockRejectsUnexpectedBlock(t *testing.T) {
+	ctx := context.Background()
+
+	genesis := mine(t, []*transaction.Transaction{testTransaction(-1, 100)}, nil)
+	child := mine(t, []*transaction.Transaction{testTransaction(-1, 110)}, genesis)
+
+	d := openOffline(t, filepath.Join(t.TempDir(), "ipfs"))
+	defer d.Close()
+
+	genesisCid, err := d.PutBlock(ctx, genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	childCid, err := d.PutBlock(ctx, child)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A node claiming the hash of another block, like a peer trying to
+	// map an honest block to its own CID
+	forged := newBlockNode(child, &genesisCid, nil)
+	forged.Hash = genesis.Hash
+
+	n, err := wrapNode(forged)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.ipfs.Dag().Add(ctx, n); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		cid  cid.Cid
+		hash []byte
+	}{
+		{"other block", childCid, genesis.Hash},
+		{"forged hash", n.Cid(), genesis.Hash},
+		{"forged header", n.Cid(), child.Hash},
+	} {
+		if _, _, err := d.GetBlock(ctx, tt.cid, tt.hash); !errors.Is(err, ErrUnexpectedBlock) {
+			t.Errorf("%s: GetBlock() error = %v, want %v", tt.name, err, ErrUnexpectedBlock)
+		}
+	}
+
+	// Known CIDs are never replaced
+	d.SetBlockCID(genesis.Hash, n.Cid())
+
+	if c, ok := d.BlockCID(genesis.Hash); !ok || c != genesisCid {
+		t.Fatalf("BlockCID() = %s, %v, want %s", c, ok, genesisCid)
+	}
+}
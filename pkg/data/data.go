@@ -7,80 +7,70 @@ import (
 	"path/filepath"
 	"sync"
 
-	config "github.com/ipfs/go-ipfs-config"
-	files "github.com/ipfs/go-ipfs-files"
+	"github.com/herlon214/ipfs-blockchain/pkg/config"
+	"github.com/ipfs/go-cid"
+	ipfsConfig "github.com/ipfs/go-ipfs-config"
 	"github.com/ipfs/go-ipfs/core"
 	"github.com/ipfs/go-ipfs/core/coreapi"
 	"github.com/ipfs/go-ipfs/core/node/libp2p"
 	"github.com/ipfs/go-ipfs/plugin/loader"
 	"github.com/ipfs/go-ipfs/repo/fsrepo"
 	icore "github.com/ipfs/interface-go-ipfs-core"
-	"github.com/ipfs/interface-go-ipfs-core/path"
 	"golang.org/x/net/context"
 )
 
 type Data struct {
-	mx     sync.Mutex
-	blocks map[string]string
-	ipfs   icore.CoreAPI
+	mx sync.Mutex
+	// cids maps the hex encoded hash of the blocks stored or connected to
+	// their CID, used to link new blocks to their parent. The CIDs of the
+	// blocks stored by PutBlock are also kept in the repo datastore
+	cids map[string]cid.Cid
+	node *core.IpfsNode
+	ipfs icore.CoreAPI
 }
 
-func New(ctx context.Context) (*Data, error) {
-	if err := createBlocksFolder(); err != nil {
-		return nil, err
-	}
-
+func New(ctx context.Context, cfg *config.Config) (*Data, error) {
 	if err := setupPlugins(""); err != nil {
 		return nil, err
 	}
 
-	// Create a Temporary Repo
-	repoPath, err := createTempRepo()
+	// Create the repo inside the data directory
+	repoPath, err := createRepo(cfg.IPFSDir(), cfg.IPFSListenAddrs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create temp repo: %s", err)
 	}
 
 	// Spawning an ephemeral IPFS node
-	ipfsNode, err := createNode(ctx, repoPath)
+	node, err := createNode(ctx, repoPath, true)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Data{
-		blocks: make(map[string]string, 0),
-		ipfs:   ipfsNode,
-	}, nil
+	return newData(node)
 }
 
-func createBlocksFolder() error {
-	// Return if already exists
-	if _, err := os.Stat("blocks"); !os.IsNotExist(err) {
-		return nil
-	}
-
-	err := os.Mkdir("blocks", 0755)
+func newData(node *core.IpfsNode) (*Data, error) {
+	// Attach the Core API to the constructed node
+	ipfs, err := coreapi.NewCoreAPI(node)
 	if err != nil {
-		return err
-	}
-
-	return nil
-}
+		node.Close()
 
-func getUnixfsNode(path string) (files.Node, error) {
-	st, err := os.Stat(path)
-	if err != nil {
 		return nil, err
 	}
 
-	f, err := files.NewSerialFile(path, false, st)
-	if err != nil {
-		return nil, err
-	}
+	return &Data{
+		cids: make(map[string]cid.Cid),
+		node: node,
+		ipfs: ipfs,
+	}, nil
+}
 
-	return f, nil
+// Close stops the IPFS node and closes its repo.
+func (d *Data) Close() error {
+	return d.node.Close()
 }
 
-func createNode(ctx context.Context, repoPath string) (icore.CoreAPI, error) {
+func createNode(ctx context.Context, repoPath string, online bool) (*core.IpfsNode, error) {
 	// Open the repo
 	repo, err := fsrepo.Open(repoPath)
 	if err != nil {
@@ -89,19 +79,13 @@ func createNode(ctx context.Context, repoPath string) (icore.CoreAPI, error) {
 
 	// Construct the node
 	nodeOptions := &core.BuildCfg{
-		Online:  true,
+		Online:  online,
 		Routing: libp2p.DHTOption, // This option sets the node to be a full DHT node (both fetching and storing DHT Records)
 		// Routing: libp2p.DHTClientOption, // This option sets the node to be a client DHT node (only fetching records)
 		Repo: repo,
 	}
 
-	node, err := core.NewNode(ctx, nodeOptions)
-	if err != nil {
-		return nil, err
-	}
-
-	// Attach the Core API to the constructed node
-	return coreapi.NewCoreAPI(node)
+	return core.NewNode(ctx, nodeOptions)
 }
 
 func setupPlugins(externalPluginsPath string) error {
@@ -123,75 +107,32 @@ func setupPlugins(externalPluginsPath string) error {
 	return nil
 }
 
-func createTempRepo() (string, error) {
-	folderName := "block-repo"
-
+func createRepo(folderName string, listenAddrs []string) (string, error) {
 	// Return if already exists
 	if _, err := os.Stat(folderName); !os.IsNotExist(err) {
 		return folderName, nil
 	}
 
-	err := os.Mkdir(folderName, 0755)
+	err := os.MkdirAll(folderName, 0755)
 	if err != nil {
-		return "", fmt.Errorf("failed to get temp dir: %s", err)
+		return "", fmt.Errorf("failed to create repo dir: %s", err)
 	}
 
 	// Create a config with default options and a 2048 bit key
-	cfg, err := config.Init(ioutil.Discard, 2048)
+	cfg, err := ipfsConfig.Init(ioutil.Discard, 2048)
 	if err != nil {
 		return "", err
 	}
 
+	if len(listenAddrs) > 0 {
+		cfg.Addresses.Swarm = listenAddrs
+	}
+
 	// Create the repo with the config
 	err = fsrepo.Init(folderName, cfg)
 	if err != nil {
-		return "", fmt.Errorf("failed to init ephemeral node: %s", err)
+		return "", fmt.Errorf("failed to init repo: %s", err)
 	}
 
 	return folderName, nil
 }
-
-func (d *Data) UploadBlock(ctx context.Context, tmpFile string) error {
-	someFile, err := getUnixfsNode(tmpFile)
-	if err != nil {
-		return err
-	}
-
-	cidFile, err := d.ipfs.Unixfs().Add(ctx, someFile)
-	if err != nil {
-		return err
-	}
-
-	d.mx.Lock()
-	d.blocks[cidFile.String()] = tmpFile
-	d.mx.Unlock()
-
-	fmt.Printf("Added file to IPFS with CID %s to path %s\n", cidFile.String(), tmpFile)
-
-	return nil
-}
-
-func (d *Data) DownloadBlock(ctx context.Context, fileCid string, filePath string) error {
-	if _, ok := d.blocks[fileCid]; ok {
-		return nil
-	}
-
-	ipfsPath := path.New(fileCid)
-
-	rootNodeFile, err := d.ipfs.Unixfs().Get(ctx, ipfsPath)
-	if err != nil {
-		return err
-	}
-
-	err = files.WriteTo(rootNodeFile, filePath)
-	if err != nil {
-		return err
-	}
-
-	d.mx.Lock()
-	fmt.Println("Downloaded block", fileCid, filePath)
-	d.blocks[fileCid] = filePath
-	d.mx.Unlock()
-
-	return nil
-}
@@ -0,0 +1,128 @@
+package data
+
+import (
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+	"github.com/ipfs/go-cid"
+	cbornode "github.com/ipfs/go-ipld-cbor"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// blockNode is the DAG-CBOR representation of a block. The parent block and
+// the transactions are links, so the chain can be walked with paths like
+// /ipfs/<cid>/prev/prev/transactions/0.
+type blockNode struct {
+	Version    int32     `refmt:"version"`
+	Height     uint64    `refmt:"height"`
+	Timestamp  int64     `refmt:"timestamp"`
+	Bits       uint32    `refmt:"bits"`
+	Prev       *cid.Cid  `refmt:"prev,omitempty"`
+	MerkleRoot []byte    `refmt:"merkleRoot"`
+	Nonce      uint64    `refmt:"nonce"`
+	Hash       []byte    `refmt:"hash"`
+	Txs        []cid.Cid `refmt:"transactions"`
+}
+
+// txNode is the DAG-CBOR representation of a transaction.
+type txNode struct {
+	ID      []byte       `refmt:"id"`
+	Inputs  []inputNode  `refmt:"inputs"`
+	Outputs []outputNode `refmt:"outputs"`
+}
+
+type inputNode struct {
+	ID        []byte `refmt:"id"`
+	Out       int    `refmt:"out"`
+	Signature []byte `refmt:"signature"`
+	PubKey    []byte `refmt:"pubKey"`
+}
+
+type outputNode struct {
+	Value      int    `refmt:"value"`
+	PubKeyHash []byte `refmt:"pubKeyHash"`
+}
+
+func init() {
+	cbornode.RegisterCborType(blockNode{})
+	cbornode.RegisterCborType(txNode{})
+	cbornode.RegisterCborType(inputNode{})
+	cbornode.RegisterCborType(outputNode{})
+}
+
+func wrapNode(obj interface{}) (*cbornode.Node, error) {
+	return cbornode.WrapObject(obj, mh.SHA2_256, -1)
+}
+
+func newTxNode(tx *transaction.Transaction) *txNode {
+	n := &txNode{ID: tx.ID}
+
+	for _, in := range tx.Inputs {
+		n.Inputs = append(n.Inputs, inputNode{
+			ID:        in.ID,
+			Out:       in.Out,
+			Signature: in.Signature,
+			PubKey:    in.PubKey,
+		})
+	}
+
+	for _, out := range tx.Outputs {
+		n.Outputs = append(n.Outputs, outputNode{
+			Value:      out.Value,
+			PubKeyHash: out.PubKeyHash,
+		})
+	}
+
+	return n
+}
+
+func (n *txNode) transaction() *transaction.Transaction {
+	tx := &transaction.Transaction{ID: n.ID}
+
+	for _, in := range n.Inputs {
+		tx.Inputs = append(tx.Inputs, transaction.Input{
+			ID:        in.ID,
+			Out:       in.Out,
+			Signature: in.Signature,
+			PubKey:    in.PubKey,
+		})
+	}
+
+	for _, out := range n.Outputs {
+		tx.Outputs = append(tx.Outputs, transaction.Output{
+			Value:      out.Value,
+			PubKeyHash: out.PubKeyHash,
+		})
+	}
+
+	return tx
+}
+
+func newBlockNode(b *block.Block, prev *cid.Cid, txs []cid.Cid) *blockNode {
+	return &blockNode{
+		Version:    b.Version,
+		Height:     b.Height,
+		Timestamp:  b.Timestamp,
+		Bits:       b.Bits,
+		Prev:       prev,
+		MerkleRoot: b.MerkleRoot,
+		Nonce:      b.Nonce,
+		Hash:       b.Hash,
+		Txs:        txs,
+	}
+}
+
+func (n *blockNode) block(prevHash []byte, txs []*transaction.Transaction) *block.Block {
+	return &block.Block{
+		Header: block.Header{
+			Version:    n.Version,
+			Height:     n.Height,
+			Timestamp:  n.Timestamp,
+			Bits:       n.Bits,
+			PrevHash:   prevHash,
+			MerkleRoot: n.MerkleRoot,
+			Nonce:      n.Nonce,
+		},
+		Hash:         n.Hash,
+		Transactions: txs,
+	}
+}
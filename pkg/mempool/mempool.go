@@ -0,0 +1,369 @@
+package mempool
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+)
+
+// DefaultMaxSize is the default limit of the serialized size of all the
+// transactions in the pool
+const DefaultMaxSize = 32 << 20
+
+var (
+	ErrAlreadyExists      = errors.New("transaction already in the pool")
+	ErrInvalidTransaction = errors.New("invalid transaction")
+	ErrDoubleSpend        = errors.New("transaction spends an output already spent")
+	ErrPoolFull           = errors.New("pool is full and the transaction fee rate is too low")
+)
+
+// Chain is the view of the blockchain the pool needs to validate
+// transactions.
+type Chain interface {
+	UnspentOutput(txID []byte, idx int) (transaction.Output, bool, error)
+}
+
+type entry struct {
+	tx    *transaction.Transaction
+	fee   int
+	size  int
+	added time.Time
+}
+
+// feeRate is the fee paid per byte of serialized transaction.
+func (e *entry) feeRate() float64 {
+	return float64(e.fee) / float64(e.size)
+}
+
+// Pool holds validated transactions waiting to be included in a block.
+type Pool struct {
+	mx sync.RWMutex
+
+	chain   Chain
+	maxSize int
+	size    int
+
+	txs map[string]*entry
+	// spent maps each outpoint spent by a pool entry to the entry ID
+	spent map[string]string
+
+	// OnAdd, when set, is called with every transaction admitted to the
+	// pool. It must not block.
+	OnAdd func(tx *transaction.Transaction)
+}
+
+func New(c Chain, maxSize int) *Pool {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+
+	return &Pool{
+		chain:   c,
+		maxSize: maxSize,
+		txs:     make(map[string]*entry),
+		spent:   make(map[string]string),
+	}
+}
+
+// Add validates the transaction against the chain and the other pool entries
+// and adds it to the pool. When the pool goes over its size limit the entries
+// with the lowest fee rate are evicted.
+func (p *Pool) Add(tx *transaction.Transaction) error {
+	if err := p.admit(tx); err != nil {
+		return err
+	}
+
+	if p.OnAdd != nil {
+		p.OnAdd(tx)
+	}
+
+	return nil
+}
+
+func (p *Pool) admit(tx *transaction.Transaction) error {
+	id := hex.EncodeToString(tx.ID)
+
+	if tx.IsCoinBase() {
+		return fmt.Errorf("%w: %s: coinbase transactions are only valid inside blocks", ErrInvalidTransaction, id)
+	}
+
+	if !tx.ValidID() {
+		return fmt.Errorf("%w: %s: ID does not match its content", ErrInvalidTransaction, id)
+	}
+
+	outputValue, err := tx.CheckValues()
+	if err != nil {
+		return fmt.Errorf("%w: %s: %s", ErrInvalidTransaction, id, err)
+	}
+
+	data, err := tx.Serialize()
+	if err != nil {
+		return err
+	}
+
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	if _, ok := p.txs[id]; ok {
+		return fmt.Errorf("%w: %s", ErrAlreadyExists, id)
+	}
+
+	// Only the spent outputs are needed to check the signatures
+	prevTxs := make(map[string]transaction.Transaction)
+	inputValue := 0
+	inputs := make(map[string]bool, len(tx.Inputs))
+	for _, in := range tx.Inputs {
+		key := outpointKey(in.ID, in.Out)
+
+		if inputs[key] {
+			return fmt.Errorf("%w: %s: output %s is spent twice", ErrInvalidTransaction, id, key)
+		}
+		inputs[key] = true
+
+		if spender, ok := p.spent[key]; ok {
+			return fmt.Errorf("%w: %s: output %s is spent by %s", ErrDoubleSpend, id, key, spender)
+		}
+
+		out, ok, err := p.chain.UnspentOutput(in.ID, in.Out)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return fmt.Errorf("%w: %s: output %s is missing or spent", ErrDoubleSpend, id, key)
+		}
+
+		inputValue, err = transaction.AddValues(inputValue, out.Value)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %s", ErrInvalidTransaction, id, err)
+		}
+
+		prevID := hex.EncodeToString(in.ID)
+		prevTx := prevTxs[prevID]
+		prevTx.ID = in.ID
+		for len(prevTx.Outputs) <= in.Out {
+			prevTx.Outputs = append(prevTx.Outputs, transaction.Output{})
+		}
+		prevTx.Outputs[in.Out] = out
+		prevTxs[prevID] = prevTx
+	}
+
+	if outputValue > inputValue {
+		return fmt.Errorf("%w: %s: spends %d but only has %d", ErrInvalidTransaction, id, outputValue, inputValue)
+	}
+
+	if !tx.Verify(prevTxs) {
+		return fmt.Errorf("%w: %s: invalid signature", ErrInvalidTransaction, id)
+	}
+
+	e := &entry{
+		tx:    tx,
+		fee:   inputValue - outputValue,
+		size:  len(data),
+		added: time.Now(),
+	}
+
+	p.add(id, e)
+
+	for p.size > p.maxSize {
+		lowest := p.lowestFeeRate()
+		p.remove(lowest)
+
+		if lowest == id {
+			return ErrPoolFull
+		}
+	}
+
+	return nil
+}
+
+// Get returns the pool transaction with the given ID.
+func (p *Pool) Get(ID []byte) (*transaction.Transaction, bool) {
+	p.mx.RLock()
+	defer p.mx.RUnlock()
+
+	e, ok := p.txs[hex.EncodeToString(ID)]
+	if !ok {
+		return nil, false
+	}
+
+	return e.tx, true
+}
+
+func (p *Pool) Has(ID []byte) bool {
+	_, ok := p.Get(ID)
+
+	return ok
+}
+
+// Fee returns the fee paid by the pool transaction with the given ID.
+func (p *Pool) Fee(ID []byte) (int, bool) {
+	p.mx.RLock()
+	defer p.mx.RUnlock()
+
+	e, ok := p.txs[hex.EncodeToString(ID)]
+	if !ok {
+		return 0, false
+	}
+
+	return e.fee, true
+}
+
+func (p *Pool) Remove(ID []byte) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	p.remove(hex.EncodeToString(ID))
+}
+
+// Count returns the number of transactions in the pool.
+func (p *Pool) Count() int {
+	p.mx.RLock()
+	defer p.mx.RUnlock()
+
+	return len(p.txs)
+}
+
+// Size returns the serialized size of all the transactions in the pool.
+func (p *Pool) Size() int {
+	p.mx.RLock()
+	defer p.mx.RUnlock()
+
+	return p.size
+}
+
+// Transactions returns every pool transaction, highest fee rate first.
+func (p *Pool) Transactions() []*transaction.Transaction {
+	return p.Select(0)
+}
+
+// Select returns the transactions with the highest fee rate whose serialized
+// size fits in maxSize, to be included in a new block. A maxSize lower or
+// equal to zero selects every transaction.
+func (p *Pool) Select(maxSize int) []*transaction.Transaction {
+	p.mx.RLock()
+	defer p.mx.RUnlock()
+
+	var result []*transaction.Transaction
+	size := 0
+
+	for _, e := range p.sorted() {
+		if maxSize > 0 && size+e.size > maxSize {
+			continue
+		}
+
+		result = append(result, e.tx)
+		size += e.size
+	}
+
+	return result
+}
+
+// RemoveBlock drops the pool transactions included in the block, along with
+// the ones spending an output also spent by the block.
+func (p *Pool) RemoveBlock(b *block.Block) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	for _, tx := range b.Transactions {
+		p.remove(hex.EncodeToString(tx.ID))
+
+		if tx.IsCoinBase() {
+			continue
+		}
+
+		for _, in := range tx.Inputs {
+			if spender, ok := p.spent[outpointKey(in.ID, in.Out)]; ok {
+				p.remove(spender)
+			}
+		}
+	}
+}
+
+// Revalidate checks the given transactions against the chain again, dropping
+// the ones no longer valid. Transactions not in the pool are ignored.
+func (p *Pool) Revalidate(txs []*transaction.Transaction) {
+	for _, tx := range txs {
+		if !p.Has(tx.ID) {
+			continue
+		}
+
+		p.Remove(tx.ID)
+
+		// The error is expected for the transactions being dropped
+		_ = p.admit(tx)
+	}
+}
+
+// ReturnBlock adds back the transactions of a block disconnected from the
+// chain. The ones no longer valid against the new chain are dropped.
+func (p *Pool) ReturnBlock(b *block.Block) {
+	for _, tx := range b.Transactions {
+		if tx.IsCoinBase() {
+			continue
+		}
+
+		// The error is expected for transactions conflicting with the
+		// blocks that replaced b
+		_ = p.Add(tx)
+	}
+}
+
+func (p *Pool) add(id string, e *entry) {
+	p.txs[id] = e
+	p.size += e.size
+
+	for _, in := range e.tx.Inputs {
+		p.spent[outpointKey(in.ID, in.Out)] = id
+	}
+}
+
+func (p *Pool) remove(id string) {
+	e, ok := p.txs[id]
+	if !ok {
+		return
+	}
+
+	for _, in := range e.tx.Inputs {
+		delete(p.spent, outpointKey(in.ID, in.Out))
+	}
+
+	p.size -= e.size
+	delete(p.txs, id)
+}
+
+func (p *Pool) lowestFeeRate() string {
+	sorted := p.sorted()
+	last := sorted[len(sorted)-1]
+
+	return hex.EncodeToString(last.tx.ID)
+}
+
+// sorted returns the entries by fee rate, highest first, breaking ties by
+// the time they were added.
+func (p *Pool) sorted() []*entry {
+	entries := make([]*entry, 0, len(p.txs))
+	for _, e := range p.txs {
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].feeRate() != entries[j].feeRate() {
+			return entries[i].feeRate() > entries[j].feeRate()
+		}
+
+		return entries[i].added.Before(entries[j].added)
+	})
+
+	return entries
+}
+
+func outpointKey(txID []byte, idx int) string {
+	return fmt.Sprintf("%x:%d", txID, idx)
+}
@@ -0,0 +1,221 @@
+package mempool
+
+import (
+	"encoding/hex"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+	"github.com/herlon214/ipfs-blockchain/pkg/wallets"
+)
+
+// testChain holds unspent outputs.
+type testChain struct {
+	utxos map[string]transaction.Output
+}
+
+func (c *testChain) UnspentOutput(txID []byte, idx int) (transaction.Output, bool, error) {
+	out, ok := c.utxos[outpointKey(txID, idx)]
+
+	return out, ok, nil
+}
+
+var (
+	owner  = newWallet()
+	prevID = []byte("previous transaction")
+	// prevTx is the transaction spent by the test transactions, its last
+	// output is already spent
+	prevTx = transaction.Transaction{
+		ID: prevID,
+		Outputs: []transaction.Output{
+			{Value: 100, PubKeyHash: owner.PublicKeyHash()},
+			{Value: math.MaxInt, PubKeyHash: owner.PublicKeyHash()},
+			{Value: math.MaxInt, PubKeyHash: owner.PublicKeyHash()},
+			{Value: 50, PubKeyHash: owner.PublicKeyHash()},
+		},
+	}
+)
+
+func newWallet() *wallets.Wallet {
+	w, err := wallets.NewWallet()
+	if err != nil {
+		panic(err)
+	}
+
+	return w
+}
+
+func newTestPool() *Pool {
+	utxos := make(map[string]transaction.Output)
+	for idx, out := range prevTx.Outputs[:len(prevTx.Outputs)-1] {
+		utxos[outpointKey(prevID, idx)] = out
+	}
+
+	return New(&testChain{utxos: utxos}, 0)
+}
+
+// newTx returns a transaction spending the given outputs of prevTx, signed
+// by their owner.
+func newTx(t *testing.T, outs []int, values ...int) *transaction.Transaction {
+	t.Helper()
+
+	var inputs []transaction.Input
+	for _, out := range outs {
+		inputs = append(inputs, transaction.Input{ID: prevID, Out: out, PubKey: owner.PublicKey})
+	}
+
+	var outputs []transaction.Output
+	for _, value := range values {
+		outputs = append(outputs, transaction.Output{Value: value, PubKeyHash: []byte("hash")})
+	}
+
+	tx, err := transaction.New(inputs, outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tx.Sign(owner.PrivateKey, map[string]transaction.Transaction{hex.EncodeToString(prevID): prevTx}); err != nil {
+		t.Fatal(err)
+	}
+
+	return tx
+}
+
+func TestAddRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		tx    func(t *testing.T) *transaction.Transaction
+		match error
+	}{
+		{
+			name:  "negative output",
+			tx:    func(t *testing.T) *transaction.Transaction { return newTx(t, []int{0}, -1000000, 1000100) },
+			match: ErrInvalidTransaction,
+		},
+		{
+			name:  "zero output",
+			tx:    func(t *testing.T) *transaction.Transaction { return newTx(t, []int{0}, 0, 50) },
+			match: ErrInvalidTransaction,
+		},
+		{
+			name:  "no inputs",
+			tx:    func(t *testing.T) *transaction.Transaction { return newTx(t, nil, 50) },
+			match: ErrInvalidTransaction,
+		},
+		{
+			name:  "no outputs",
+			tx:    func(t *testing.T) *transaction.Transaction { return newTx(t, []int{0}) },
+			match: ErrInvalidTransaction,
+		},
+		{
+			name:  "same output spent twice",
+			tx:    func(t *testing.T) *transaction.Transaction { return newTx(t, []int{0, 0}, 150) },
+			match: ErrInvalidTransaction,
+		},
+		{
+			name:  "input overflow",
+			tx:    func(t *testing.T) *transaction.Transaction { return newTx(t, []int{1, 2}, 50) },
+			match: ErrInvalidTransaction,
+		},
+		{
+			name:  "output overflow",
+			tx:    func(t *testing.T) *transaction.Transaction { return newTx(t, []int{0}, math.MaxInt, 2) },
+			match: ErrInvalidTransaction,
+		},
+		{
+			name:  "spends more than its inputs",
+			tx:    func(t *testing.T) *transaction.Transaction { return newTx(t, []int{0}, 101) },
+			match: ErrInvalidTransaction,
+		},
+		{
+			name: "invalid signature",
+			tx: func(t *testing.T) *transaction.Transaction {
+				tx := newTx(t, []int{0}, 50)
+				tx.Inputs[0].Signature[0] ^= 1
+
+				return tx
+			},
+			match: ErrInvalidTransaction,
+		},
+		{
+			name:  "missing output",
+			tx:    func(t *testing.T) *transaction.Transaction { return newTx(t, []int{3}, 50) },
+			match: ErrDoubleSpend,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := newTestPool()
+
+			if err := pool.Add(tt.tx(t)); !errors.Is(err, tt.match) {
+				t.Fatalf("Add() error = %v, want %v", err, tt.match)
+			}
+
+			if pool.Count() != 0 {
+				t.Fatalf("Count() = %d, want 0", pool.Count())
+			}
+		})
+	}
+}
+
+func TestAddDoubleSpend(t *testing.T) {
+	pool := newTestPool()
+
+	tx := newTx(t, []int{0}, 60, 30)
+	if err := pool.Add(tx); err != nil {
+		t.Fatal(err)
+	}
+
+	if fee, ok := pool.Fee(tx.ID); !ok || fee != 10 {
+		t.Fatalf("Fee() = %d, %v, want 10, true", fee, ok)
+	}
+
+	if err := pool.Add(tx); !errors.Is(err, ErrAlreadyExists) {
+		t.Fatalf("Add() error = %v, want %v", err, ErrAlreadyExists)
+	}
+
+	if err := pool.Add(newTx(t, []int{0}, 80)); !errors.Is(err, ErrDoubleSpend) {
+		t.Fatalf("Add() error = %v, want %v", err, ErrDoubleSpend)
+	}
+}
+
+func TestReturnBlock(t *testing.T) {
+	pool := newTestPool()
+
+	coinbaseTx, err := transaction.New(
+		[]transaction.Input{{Out: -1, PubKey: []byte("coinbase")}},
+		[]transaction.Output{{Value: transaction.Reward, PubKeyHash: []byte("hash")}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	returned := newTx(t, []int{0}, 60, 30)
+	conflicting := newTx(t, []int{1}, 50)
+
+	// The block replacing the disconnected one spent the same output
+	if err := pool.Add(newTx(t, []int{1}, 40)); err != nil {
+		t.Fatal(err)
+	}
+
+	pool.ReturnBlock(block.New([]*transaction.Transaction{coinbaseTx, returned, conflicting}, prevID, 1, 1))
+
+	if !pool.Has(returned.ID) {
+		t.Fatal("disconnected transaction not returned to the pool")
+	}
+
+	if pool.Has(coinbaseTx.ID) {
+		t.Fatal("coinbase returned to the pool")
+	}
+
+	if pool.Has(conflicting.ID) {
+		t.Fatal("conflicting transaction returned to the pool")
+	}
+
+	if pool.Count() != 2 {
+		t.Fatalf("Count() = %d, want 2", pool.Count())
+	}
+}
@@ -0,0 +1,132 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+)
+
+var ErrNotFound = errors.New("data not found in merkle tree")
+
+// Leaves and inner nodes are hashed with different prefixes so an inner node
+// can never be presented as a leaf
+const (
+	leafPrefix = byte(0x00)
+	nodePrefix = byte(0x01)
+)
+
+// Tree keeps every level of the tree, from the leaves hashes up to the root.
+// Levels with an odd number of nodes pair the last node with itself, so
+// repeating the last leaves keeps the root: callers must reject duplicates.
+type Tree struct {
+	data   [][]byte
+	levels [][][]byte
+}
+
+// ProofStep is a sibling hash in the path from a leaf to the root.
+type ProofStep struct {
+	Hash []byte
+	// Left tells whether the sibling is on the left of the current node
+	Left bool
+}
+
+type Proof []ProofStep
+
+func New(data [][]byte) *Tree {
+	var leaves [][]byte
+	for _, item := range data {
+		leaves = append(leaves, hashLeaf(item))
+	}
+
+	if len(leaves) == 0 {
+		empty := sha256.Sum256([]byte{})
+		leaves = [][]byte{empty[:]}
+	}
+
+	levels := [][][]byte{leaves}
+
+	for current := leaves; len(current) > 1; {
+		var next [][]byte
+
+		for i := 0; i < len(current); i += 2 {
+			right := current[i]
+			if i+1 < len(current) {
+				right = current[i+1]
+			}
+
+			next = append(next, hashNode(current[i], right))
+		}
+
+		levels = append(levels, next)
+		current = next
+	}
+
+	return &Tree{
+		data:   data,
+		levels: levels,
+	}
+}
+
+func (t *Tree) Root() []byte {
+	return t.levels[len(t.levels)-1][0]
+}
+
+// Proof returns the sibling path from the leaf holding data up to the root.
+func (t *Tree) Proof(data []byte) (Proof, error) {
+	idx := -1
+	for i, item := range t.data {
+		if bytes.Equal(item, data) {
+			idx = i
+			break
+		}
+	}
+
+	if idx == -1 {
+		return nil, ErrNotFound
+	}
+
+	var proof Proof
+	for _, level := range t.levels[:len(t.levels)-1] {
+		siblingIdx := idx ^ 1
+		if siblingIdx >= len(level) {
+			siblingIdx = idx
+		}
+
+		proof = append(proof, ProofStep{
+			Hash: level[siblingIdx],
+			Left: siblingIdx < idx,
+		})
+
+		idx /= 2
+	}
+
+	return proof, nil
+}
+
+// VerifyProof recomputes the root from data and its sibling path and compares
+// it with the given root.
+func VerifyProof(root []byte, data []byte, proof Proof) bool {
+	hash := hashLeaf(data)
+
+	for _, step := range proof {
+		if step.Left {
+			hash = hashNode(step.Hash, hash)
+		} else {
+			hash = hashNode(hash, step.Hash)
+		}
+	}
+
+	return bytes.Equal(hash, root)
+}
+
+func hashLeaf(data []byte) []byte {
+	hash := sha256.Sum256(append([]byte{leafPrefix}, data...))
+
+	return hash[:]
+}
+
+func hashNode(left []byte, right []byte) []byte {
+	hash := sha256.Sum256(bytes.Join([][]byte{{nodePrefix}, left, right}, []byte{}))
+
+	return hash[:]
+}
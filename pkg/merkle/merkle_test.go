@@ -0,0 +1,115 @@
+package merkle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func leaves(n int) [][]byte {
+	var data [][]byte
+	for i := 0; i < n; i++ {
+		data = append(data, []byte(fmt.Sprintf("tx %d", i)))
+	}
+
+	return data
+}
+
+func TestRoot(t *testing.T) {
+	data := leaves(5)
+
+	h := make([][]byte, len(data))
+	for i, item := range data {
+		h[i] = hashLeaf(item)
+	}
+
+	tests := []struct {
+		name string
+		data [][]byte
+		want []byte
+	}{
+		{"1 leaf", data[:1], h[0]},
+		{"2 leaves", data[:2], hashNode(h[0], h[1])},
+		{"3 leaves", data[:3], hashNode(hashNode(h[0], h[1]), hashNode(h[2], h[2]))},
+		{"5 leaves", data[:5], hashNode(
+			hashNode(hashNode(h[0], h[1]), hashNode(h[2], h[3])),
+			hashNode(hashNode(h[4], h[4]), hashNode(h[4], h[4])),
+		)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(tt.data).Root(); !bytes.Equal(got, tt.want) {
+				t.Fatalf("Root() = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProof(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 5} {
+		t.Run(fmt.Sprintf("%d leaves", n), func(t *testing.T) {
+			data := leaves(n)
+			tree := New(data)
+
+			for _, item := range data {
+				proof, err := tree.Proof(item)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !VerifyProof(tree.Root(), item, proof) {
+					t.Fatalf("VerifyProof() rejected the proof of %q", item)
+				}
+			}
+
+			if _, err := tree.Proof([]byte("missing")); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Proof() error = %v, want %v", err, ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestVerifyProofRejects(t *testing.T) {
+	data := leaves(5)
+	tree := New(data)
+
+	tests := []struct {
+		name   string
+		root   []byte
+		data   []byte
+		mutate func(proof Proof)
+	}{
+		{"wrong sibling", tree.Root(), data[1], func(proof Proof) { proof[1].Hash = hashLeaf([]byte("other")) }},
+		{"wrong direction", tree.Root(), data[1], func(proof Proof) { proof[0].Left = !proof[0].Left }},
+		{"wrong root", New(leaves(4)).Root(), data[1], func(proof Proof) {}},
+		{"wrong data", tree.Root(), []byte("other"), func(proof Proof) {}},
+		{"leaf hash as data", tree.Root(), hashLeaf(data[1]), func(proof Proof) {}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proof, err := tree.Proof(data[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.mutate(proof)
+
+			if VerifyProof(tt.root, tt.data, proof) {
+				t.Fatal("VerifyProof() accepted the proof")
+			}
+		})
+	}
+}
+
+// Pairing the last node with itself gives a tree repeating its last leaves
+// the same root, blocks must reject duplicate transactions themselves.
+func TestRootRepeatingLastLeaf(t *testing.T) {
+	data := leaves(3)
+
+	if !bytes.Equal(New(data).Root(), New(append(data, data[2])).Root()) {
+		t.Fatal("repeating the last leaf changed the root")
+	}
+}
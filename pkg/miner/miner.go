@@ -0,0 +1,165 @@
+package miner
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"time"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+	"github.com/herlon214/ipfs-blockchain/pkg/chain"
+	"github.com/herlon214/ipfs-blockchain/pkg/mempool"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+)
+
+// MaxBlockSize limits the serialized size of the transactions pulled from
+// the mempool for a single block
+const MaxBlockSize = 1 << 20
+
+// ErrTipChanged is returned when another block was connected to the chain
+// while mining, making the block being mined stale.
+var ErrTipChanged = errors.New("chain tip changed while mining")
+
+type Miner struct {
+	Chain *chain.Chain
+	// Address receives the block reward and the transactions fees
+	Address string
+	// Workers is the number of goroutines searching for a nonce, it
+	// defaults to the number of CPUs
+	Workers int
+	// OnHashRate, when set, is called every second with the hashes per
+	// second computed while mining
+	OnHashRate func(rate float64)
+}
+
+func New(c *chain.Chain, address string) *Miner {
+	return &Miner{
+		Chain:   c,
+		Address: address,
+		Workers: runtime.NumCPU(),
+	}
+}
+
+// Mine mines a block with the given transactions on top of the current chain
+// tip and connects it, its coinbase collecting fees on top of the block
+// reward. It aborts when ctx is done or, returning ErrTipChanged, when
+// another block is connected in the meantime.
+func (m *Miner) Mine(ctx context.Context, txs []*transaction.Transaction, fees int) (*block.Block, error) {
+	tipChanged := m.Chain.TipChanged()
+
+	coinbaseTx, err := transaction.CoinBase(m.Address, "", fees)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := m.Chain.NewBlockTemplate(append([]*transaction.Transaction{coinbaseTx}, txs...))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	staleTip := make(chan struct{})
+	go func() {
+		select {
+		case <-tipChanged:
+			close(staleTip)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	pow := block.NewProof(b)
+	go m.reportHashRate(ctx, pow)
+
+	nonce, hash, err := pow.Run(ctx, m.Workers)
+	if err != nil {
+		select {
+		case <-staleTip:
+			return nil, ErrTipChanged
+		default:
+		}
+
+		return nil, err
+	}
+
+	b.Nonce = nonce
+	b.Hash = hash
+
+	if err := m.Chain.ConnectBlock(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// MinePool mines a block with the transactions of the pool paying the
+// highest fee rate, removing them from the pool once the block is connected.
+// When the block can not be built or is rejected by the chain the selected
+// transactions are checked again, dropping the ones no longer valid so the
+// next block does not include them.
+func (m *Miner) MinePool(ctx context.Context, pool *mempool.Pool) (*block.Block, error) {
+	var txs []*transaction.Transaction
+	fees := 0
+
+	for _, tx := range pool.Select(MaxBlockSize) {
+		// The fee was computed when the transaction was admitted, the ones
+		// evicted since the selection are left out
+		fee, ok := pool.Fee(tx.ID)
+		if !ok {
+			continue
+		}
+
+		var err error
+		fees, err = transaction.AddValues(fees, fee)
+		if err != nil {
+			return nil, err
+		}
+
+		txs = append(txs, tx)
+	}
+
+	b, err := m.Mine(ctx, txs, fees)
+	if err != nil {
+		if ctx.Err() == nil && !errors.Is(err, ErrTipChanged) {
+			var txErr *chain.TransactionError
+			if errors.As(err, &txErr) {
+				pool.Remove(txErr.ID)
+			}
+
+			pool.Revalidate(txs)
+		}
+
+		return nil, err
+	}
+
+	pool.RemoveBlock(b)
+
+	return b, nil
+}
+
+func (m *Miner) reportHashRate(ctx context.Context, pow *block.ProofOfWork) {
+	if m.OnHashRate == nil {
+		return
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	last := pow.Hashes()
+	lastTime := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			hashes := pow.Hashes()
+			m.OnHashRate(float64(hashes-last) / now.Sub(lastTime).Seconds())
+
+			last = hashes
+			lastTime = now
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package miner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/chain"
+	"github.com/herlon214/ipfs-blockchain/pkg/config"
+	"github.com/herlon214/ipfs-blockchain/pkg/mempool"
+	"github.com/herlon214/ipfs-blockchain/pkg/params"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+	"github.com/herlon214/ipfs-blockchain/pkg/wallets"
+)
+
+func newTestChain(t *testing.T, w *wallets.Wallet) *chain.Chain {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.DataDir = t.TempDir()
+	cfg.Network = params.Test.Name
+	cfg.Genesis = config.Genesis{
+		Address:   string(w.Address()),
+		Timestamp: 1600000000,
+		Message:   "test genesis",
+	}
+
+	c, err := chain.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		c.Database.Close()
+	})
+
+	return c
+}
+
+func TestMinePoolDropsRejectedTransactions(t *testing.T) {
+	w, err := wallets.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	to, err := wallets.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := newTestChain(t, w)
+	pool := mempool.New(c, 0)
+
+	pending, err := c.NewTransaction(w, string(to.Address()), 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pool.Add(pending); err != nil {
+		t.Fatal(err)
+	}
+
+	// A block received from a peer spends the same output without going
+	// through the pool, the pending transaction is now a double spend
+	conflict, err := c.NewTransaction(w, string(to.Address()), 20, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	coinbaseTx, err := transaction.CoinBase(string(w.Address()), "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.AddBlock([]*transaction.Transaction{coinbaseTx, conflict}); err != nil {
+		t.Fatal(err)
+	}
+
+	m := New(c, string(w.Address()))
+	m.Workers = 1
+
+	if _, err := m.MinePool(context.Background(), pool); err == nil {
+		t.Fatal("MinePool() mined a block with a double spend")
+	}
+
+	if pool.Has(pending.ID) {
+		t.Fatal("rejected transaction is still in the pool")
+	}
+
+	b, err := m.MinePool(context.Background(), pool)
+	if err != nil {
+		t.Fatalf("MinePool() after the rejection = %v", err)
+	}
+
+	if b.Height != 2 {
+		t.Fatalf("mined block height = %d, want 2", b.Height)
+	}
+}
@@ -0,0 +1,416 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/api"
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+	"github.com/herlon214/ipfs-blockchain/pkg/chain"
+	"github.com/herlon214/ipfs-blockchain/pkg/channel"
+	"github.com/herlon214/ipfs-blockchain/pkg/config"
+	"github.com/herlon214/ipfs-blockchain/pkg/data"
+	"github.com/herlon214/ipfs-blockchain/pkg/mempool"
+	"github.com/herlon214/ipfs-blockchain/pkg/miner"
+	"github.com/herlon214/ipfs-blockchain/pkg/syncer"
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/peerstore"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/multiformats/go-multiaddr"
+)
+
+const (
+	// statusInterval is how often the local tip is advertised to the peers
+	statusInterval = 30 * time.Second
+	// miningRetryDelay is the pause after a failed mining attempt
+	miningRetryDelay = 5 * time.Second
+)
+
+var ErrNotInitialized = errors.New("node not initialized, run `blockchain node init` first")
+
+// Node is a running blockchain node: the chain and mempool, the IPFS data
+// layer storing the blocks and the libp2p host exchanging them.
+type Node struct {
+	Config *config.Config
+	Host   host.Host
+	Data   *data.Data
+	Chain  *chain.Chain
+	Pool   *mempool.Pool
+	Blocks *channel.BlockChannel
+	Txs    *channel.TxChannel
+	Syncer *syncer.Syncer
+	// Miner is nil unless mining is enabled
+	Miner *miner.Miner
+	// API is nil when the HTTP API is disabled
+	API *api.Server
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Init creates the node private key and the chain database with the
+// configured genesis block. An existing key is kept.
+func Init(cfg *config.Config) error {
+	if err := os.MkdirAll(cfg.DataDir, 0755); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(cfg.KeyPath()); os.IsNotExist(err) {
+		prvKey, _, err := crypto.GenerateKeyPair(crypto.RSA, 2048)
+		if err != nil {
+			return err
+		}
+
+		key, err := crypto.MarshalPrivateKey(prvKey)
+		if err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(cfg.KeyPath(), key, 0600); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	c, err := chain.New(cfg)
+	if err != nil {
+		return err
+	}
+
+	return c.Database.Close()
+}
+
+// Start opens the chain and the IPFS data layer, joins the block and
+// transaction topics, connects to the bootstrap peers and starts syncing,
+// and mining when enabled. The node runs until Close is called.
+func Start(ctx context.Context, cfg *config.Config) (*Node, error) {
+	keyBytes, err := os.ReadFile(cfg.KeyPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: missing key %s", ErrNotInitialized, cfg.KeyPath())
+		}
+
+		return nil, err
+	}
+
+	prvKey, err := crypto.UnmarshalPrivateKey(keyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", cfg.KeyPath(), err)
+	}
+
+	n := &Node{Config: cfg}
+	n.ctx, n.cancel = context.WithCancel(ctx)
+
+	if err := n.start(prvKey); err != nil {
+		n.Close()
+
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func (n *Node) start(prvKey crypto.PrivKey) error {
+	var err error
+
+	n.Chain, err = chain.New(n.Config)
+	if err != nil {
+		return err
+	}
+
+	n.Host, err = libp2p.New(
+		libp2p.ListenAddrStrings(n.Config.ListenAddrs...),
+		libp2p.Identity(prvKey),
+	)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Current node %s on network %s\n", n.Host.ID().Pretty(), n.Config.Network)
+	for _, addr := range n.Host.Addrs() {
+		log.Printf("Listening on %s/p2p/%s\n", addr, n.Host.ID().Pretty())
+	}
+
+	log.Println("Starting ipfs node...")
+	n.Data, err = data.New(n.ctx, n.Config)
+	if err != nil {
+		return err
+	}
+
+	// Keep the mempool in line with the best chain, including the
+	// reorganizations
+	n.Pool = mempool.New(n.Chain, mempool.DefaultMaxSize)
+	n.Chain.Subscribe(func(e chain.Event) {
+		switch e.Type {
+		case chain.BlockConnected:
+			n.Pool.RemoveBlock(e.Block)
+		case chain.BlockDisconnected:
+			n.Pool.ReturnBlock(e.Block)
+		}
+	})
+
+	// Stream the chain and mempool changes to the API subscribers. The hook
+	// is set before any transaction or block is received so none is missed.
+	var events *api.Hub
+	if n.Config.API.Listen != "" {
+		events = api.NewHub(n.Chain)
+		n.Chain.Subscribe(events.ChainEvent)
+		n.Pool.OnAdd = events.TransactionAdded
+	}
+
+	for _, addr := range n.Config.BootstrapPeers {
+		log.Println("Connecting to", addr)
+		if err := connectToPeer(n.ctx, n.Host, addr); err != nil {
+			return fmt.Errorf("bootstrap peer %s: %w", addr, err)
+		}
+	}
+
+	// Add all the local blocks to ipfs, oldest first so every block can
+	// link to its parent
+	if err := n.putBlocks(); err != nil {
+		return err
+	}
+
+	ps, err := pubsub.NewGossipSub(n.ctx, n.Host)
+	if err != nil {
+		return err
+	}
+
+	n.Blocks, err = channel.NewBlockChannel(n.ctx, ps, n.Host.ID(), n.Config, n.Data, n.Chain)
+	if err != nil {
+		return err
+	}
+
+	n.Txs, err = channel.NewTxChannel(n.ctx, ps, n.Host.ID(), n.Config, n.Pool)
+	if err != nil {
+		return err
+	}
+
+	// Sync to the tips announced by other peers
+	n.Syncer = syncer.New(n.Chain, n.Data)
+	n.Syncer.OnProgress = func(p syncer.Progress) {
+		log.Printf("Syncing: height %d/%d, %d fetched, %d connected, ETA %s\n", p.Height, p.TargetHeight, p.Fetched, p.Connected, p.ETA.Round(time.Second))
+	}
+
+	n.Blocks.OnTip = func(from peer.ID, hash []byte, height uint64, cid string) {
+		n.Syncer.Request(syncer.Target{Hash: hash, Height: height, CID: cid})
+	}
+
+	n.run(func() {
+		if err := n.Syncer.Run(n.ctx); err != nil && n.ctx.Err() == nil {
+			log.Printf("Sync stopped: %s\n", err)
+		}
+	})
+	n.run(n.broadcastStatus)
+
+	if n.Config.API.Listen != "" {
+		ln, err := net.Listen("tcp", n.Config.API.Listen)
+		if err != nil {
+			return fmt.Errorf("api: %w", err)
+		}
+
+		n.API = api.New(n.Chain, n.Pool, n.Txs, n.Blocks)
+		n.API.Token = n.Config.API.Token
+		n.API.Events = events
+
+		log.Println("API listening on", ln.Addr())
+		n.run(func() {
+			defer n.API.Events.Close()
+
+			if err := n.API.Serve(n.ctx, ln); err != nil {
+				log.Printf("API stopped: %s\n", err)
+			}
+		})
+	}
+
+	if n.Config.Mining.Enabled {
+		n.Miner = miner.New(n.Chain, n.Config.Mining.Address)
+		if n.Config.Mining.Workers > 0 {
+			n.Miner.Workers = n.Config.Mining.Workers
+		}
+
+		n.run(n.mine)
+	}
+
+	return nil
+}
+
+// Close stops the background work of the node and closes the IPFS node, the
+// libp2p host and the chain database.
+func (n *Node) Close() error {
+	n.cancel()
+	n.wg.Wait()
+
+	var errs []error
+
+	if n.Data != nil {
+		errs = append(errs, n.Data.Close())
+	}
+
+	if n.Host != nil {
+		errs = append(errs, n.Host.Close())
+	}
+
+	if n.Chain != nil {
+		errs = append(errs, n.Chain.Database.Close())
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// run runs fn in the background, Close waits for it to return.
+func (n *Node) run(fn func()) {
+	n.wg.Add(1)
+
+	go func() {
+		defer n.wg.Done()
+
+		fn()
+	}()
+}
+
+// Announce stores the block in IPFS and announces it to the other peers.
+func (n *Node) Announce(b *block.Block) error {
+	c, err := n.Data.PutBlock(n.ctx, b)
+	if err != nil {
+		return err
+	}
+
+	return n.Blocks.AnnounceBlock(b, c.String())
+}
+
+// putBlocks stores in IPFS the blocks of the best chain this node did not
+// store yet, like the ones connected while it was offline.
+func (n *Node) putBlocks() error {
+	var blocks []*block.Block
+
+	it := n.Chain.Iterator()
+	for {
+		b, err := it.Next()
+		if err != nil {
+			return err
+		}
+
+		if b == nil {
+			break
+		}
+
+		// The parents of a stored block were stored or fetched before it
+		if _, ok := n.Data.StoredCID(b.Hash); ok {
+			break
+		}
+
+		blocks = append(blocks, b)
+	}
+
+	for i := len(blocks) - 1; i >= 0; i-- {
+		if _, err := n.Data.PutBlock(n.ctx, blocks[i]); err != nil {
+			return err
+		}
+	}
+
+	if len(blocks) > 0 {
+		log.Printf("Added %d blocks to IPFS\n", len(blocks))
+	}
+
+	return nil
+}
+
+// mine mines blocks with the mempool transactions until the node is closed.
+func (n *Node) mine() {
+	for n.ctx.Err() == nil {
+		b, err := n.Miner.MinePool(n.ctx, n.Pool)
+		if err != nil {
+			if n.ctx.Err() != nil || errors.Is(err, miner.ErrTipChanged) {
+				continue
+			}
+
+			log.Printf("Mining failed: %s\n", err)
+
+			select {
+			case <-n.ctx.Done():
+			case <-time.After(miningRetryDelay):
+			}
+
+			continue
+		}
+
+		log.Printf("Mined block %x at height %d\n", b.Hash, b.Height)
+
+		if err := n.Announce(b); err != nil {
+			log.Printf("Failed to announce block %x: %s\n", b.Hash, err)
+		}
+	}
+}
+
+// broadcastStatus advertises the local tip periodically so the peers behind
+// it can sync.
+func (n *Node) broadcastStatus() {
+	ticker := time.NewTicker(statusInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		tip, err := n.Chain.GetBlock(n.Chain.LastHash())
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		tipCid, ok := n.Data.BlockCID(tip.Hash)
+		if !ok {
+			tipCid, err = n.Data.PutBlock(n.ctx, tip)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+		}
+
+		err = n.Blocks.BroadcastStatus(tip, tipCid.String())
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+func connectToPeer(ctx context.Context, h host.Host, destination string) error {
+	// Turn the destination into a multiaddr.
+	maddr, err := multiaddr.NewMultiaddr(destination)
+	if err != nil {
+		return err
+	}
+
+	// Extract the peer ID from the multiaddr.
+	info, err := peer.AddrInfoFromP2pAddr(maddr)
+	if err != nil {
+		return err
+	}
+
+	// Add the destination's peer multiaddress in the peerstore.
+	// This will be used during connection and stream creation by libp2p.
+	h.Peerstore().AddAddrs(info.ID, info.Addrs, peerstore.PermanentAddrTTL)
+
+	return h.Connect(ctx, *info)
+}
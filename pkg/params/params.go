@@ -0,0 +1,79 @@
+package params
+
+import "time"
+
+// Genesis describes the genesis block of a network. Nodes on the same
+// network mine the same genesis block from it.
+type Genesis struct {
+	// Address receives the genesis reward
+	Address string
+	// Timestamp is the unix time of the block
+	Timestamp int64
+	// Message is the coinbase data
+	Message string
+}
+
+// Params holds the consensus rules that change between networks.
+type Params struct {
+	Name string
+
+	Genesis Genesis
+
+	// InitialBits is the difficulty of the genesis block, as the number of
+	// leading zero bits required in a block hash
+	InitialBits uint32
+	MinBits     uint32
+	MaxBits     uint32
+
+	// TargetSpacing is the expected time between two blocks
+	TargetSpacing time.Duration
+	// RetargetInterval is the number of blocks between difficulty
+	// adjustments
+	RetargetInterval uint64
+	// MaxAdjustment limits how many bits the difficulty moves on each
+	// adjustment
+	MaxAdjustment uint32
+
+	// MaxReorgDepth is the maximum number of blocks disconnected from the
+	// best chain to switch to a heavier branch
+	MaxReorgDepth uint64
+}
+
+var Main = &Params{
+	Name: "main",
+	Genesis: Genesis{
+		Address:   "14CKbxFRD4ZLrTW2NyjprsmF9uwWHpRhzZ",
+		Timestamp: 1640995200,
+		Message:   "ipfs-blockchain main network genesis",
+	},
+	InitialBits:      15,
+	MinBits:          8,
+	MaxBits:          64,
+	TargetSpacing:    30 * time.Second,
+	RetargetInterval: 20,
+	MaxAdjustment:    2,
+	MaxReorgDepth:    100,
+}
+
+// Test uses a tiny difficulty so blocks are mined almost instantly.
+var Test = &Params{
+	Name: "test",
+	Genesis: Genesis{
+		Address:   "1Ly731HQz58okpTBJZ5X36tndk7rp8RcVB",
+		Timestamp: 1640995200,
+		Message:   "ipfs-blockchain test network genesis",
+	},
+	InitialBits:      1,
+	MinBits:          1,
+	MaxBits:          8,
+	TargetSpacing:    time.Second,
+	RetargetInterval: 5,
+	MaxAdjustment:    1,
+	MaxReorgDepth:    10,
+}
+
+// Networks holds the parameters of every known network by name.
+var Networks = map[string]*Params{
+	Main.Name: Main,
+	Test.Name: Test,
+}
@@ -0,0 +1,8 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+
+package prompt
+
+// disableEcho is not supported on this platform, the passphrase is echoed.
+func disableEcho(fd int) func() {
+	return func() {}
+}
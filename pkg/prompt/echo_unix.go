@@ -0,0 +1,26 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package prompt
+
+import "golang.org/x/sys/unix"
+
+// disableEcho turns off the echo of the terminal, returning the function
+// restoring it. Nothing is changed when fd is not a terminal.
+func disableEcho(fd int) func() {
+	state, err := unix.IoctlGetTermios(fd, getTermios)
+	if err != nil {
+		return func() {}
+	}
+
+	noEcho := *state
+	noEcho.Lflag &^= unix.ECHO
+	noEcho.Lflag |= unix.ICANON | unix.ISIG
+
+	if err := unix.IoctlSetTermios(fd, setTermios, &noEcho); err != nil {
+		return func() {}
+	}
+
+	return func() {
+		unix.IoctlSetTermios(fd, setTermios, state)
+	}
+}
@@ -0,0 +1,73 @@
+package prompt
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var ErrPassphraseMismatch = errors.New("passphrases do not match")
+
+// stdin is shared by the prompts so buffered input is not lost between them
+var stdin = bufio.NewReader(os.Stdin)
+
+// Passphrase returns the value of the environment variable when it is set,
+// otherwise it asks for the passphrase on the terminal without echoing it.
+func Passphrase(env string, label string) ([]byte, error) {
+	if value, ok := os.LookupEnv(env); ok {
+		return []byte(value), nil
+	}
+
+	return read(label)
+}
+
+// NewPassphrase works like Passphrase, asking twice on the terminal to rule
+// out typos. Empty passphrases are rejected.
+func NewPassphrase(env string, label string) ([]byte, error) {
+	if value, ok := os.LookupEnv(env); ok {
+		if value == "" {
+			return nil, fmt.Errorf("%s is empty", env)
+		}
+
+		return []byte(value), nil
+	}
+
+	passphrase, err := read(label)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(passphrase) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+
+	confirmation, err := read("Repeat " + label)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(passphrase, confirmation) {
+		return nil, ErrPassphraseMismatch
+	}
+
+	return passphrase, nil
+}
+
+func read(label string) ([]byte, error) {
+	fmt.Fprintf(os.Stderr, "%s: ", label)
+
+	restore := disableEcho(int(os.Stdin.Fd()))
+	line, err := stdin.ReadBytes('\n')
+	restore()
+
+	// The newline typed was not echoed
+	fmt.Fprintln(os.Stderr)
+
+	if err != nil && len(line) == 0 {
+		return nil, fmt.Errorf("reading passphrase: %w", err)
+	}
+
+	return bytes.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,71 @@
+package syncer
+
+import "time"
+
+// Progress describes the sync being run.
+type Progress struct {
+	// Height is the height of the local chain tip
+	Height uint64
+	// TargetHeight is the height of the tip being synced to
+	TargetHeight uint64
+	// Fetched and Connected count the blocks fetched from the network, or
+	// loaded from an interrupted sync, and connected to the chain
+	Fetched   int
+	Connected int
+	// ETA estimates the time left to reach the target, it is zero until
+	// the first block is processed
+	ETA time.Duration
+}
+
+// Progress returns the progress of the current or last sync.
+func (s *Syncer) Progress() Progress {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return s.progress
+}
+
+func (s *Syncer) start(height uint64, targetHeight uint64) {
+	s.mx.Lock()
+	s.started = time.Now()
+	s.progress = Progress{
+		Height:       height,
+		TargetHeight: targetHeight,
+	}
+	s.mx.Unlock()
+}
+
+func (s *Syncer) update(fn func(p *Progress)) {
+	s.mx.Lock()
+
+	fn(&s.progress)
+	s.progress.ETA = s.eta()
+	p := s.progress
+
+	s.mx.Unlock()
+
+	if s.OnProgress != nil {
+		s.OnProgress(p)
+	}
+}
+
+// eta estimates the time left from the rate blocks were processed so far.
+// Every missing block is fetched once and connected once.
+func (s *Syncer) eta() time.Duration {
+	p := s.progress
+
+	done := p.Fetched + p.Connected
+	if done == 0 || p.TargetHeight <= p.Height {
+		return 0
+	}
+
+	missing := int(p.TargetHeight - p.Height)
+	remaining := 2*(missing+p.Connected) - done
+	if remaining <= 0 {
+		return 0
+	}
+
+	perBlock := time.Since(s.started) / time.Duration(done)
+
+	return perBlock * time.Duration(remaining)
+}
@@ -0,0 +1,130 @@
+package syncer
+
+import (
+	"bytes"
+	"encoding/gob"
+
+	"github.com/dgraph-io/badger"
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+)
+
+var (
+	targetKey   = []byte("sync-target")
+	entryPrefix = []byte("sync-block-")
+	statePrefix = []byte("sync-")
+)
+
+// entry is a block fetched but not yet connected to the chain.
+type entry struct {
+	Block *block.Block
+	// CID is the CID the block was fetched from, recorded once the block
+	// is connected
+	CID string
+	// ParentCID is empty for the genesis block
+	ParentCID string
+}
+
+func (s *Syncer) loadTarget() (Target, bool, error) {
+	var t Target
+
+	ok, err := s.get(targetKey, &t)
+
+	return t, ok, err
+}
+
+func (s *Syncer) saveTarget(t Target) error {
+	return s.set(targetKey, t)
+}
+
+func (s *Syncer) loadEntry(hash []byte) (*entry, bool, error) {
+	var e entry
+
+	ok, err := s.get(entryKey(hash), &e)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+
+	return &e, true, nil
+}
+
+func (s *Syncer) saveEntry(e *entry) error {
+	return s.set(entryKey(e.Block.Hash), e)
+}
+
+func (s *Syncer) deleteEntry(hash []byte) error {
+	return s.Chain.Database.Update(func(txn *badger.Txn) error {
+		return txn.Delete(entryKey(hash))
+	})
+}
+
+// clear drops the target and every block fetched for it.
+func (s *Syncer) clear() error {
+	var keys [][]byte
+
+	err := s.Chain.Database.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(statePrefix); it.ValidForPrefix(statePrefix); it.Next() {
+			keys = append(keys, it.Item().KeyCopy(nil))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	batch := s.Chain.Database.NewWriteBatch()
+	defer batch.Cancel()
+
+	for _, key := range keys {
+		if err := batch.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	return batch.Flush()
+}
+
+func (s *Syncer) get(key []byte, v interface{}) (bool, error) {
+	found := false
+
+	err := s.Chain.Database.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		found = true
+
+		return item.Value(func(val []byte) error {
+			return gob.NewDecoder(bytes.NewReader(val)).Decode(v)
+		})
+	})
+
+	return found, err
+}
+
+func (s *Syncer) set(key []byte, v interface{}) error {
+	var buf bytes.Buffer
+
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+
+	return s.Chain.Database.Update(func(txn *badger.Txn) error {
+		return txn.Set(key, buf.Bytes())
+	})
+}
+
+func entryKey(hash []byte) []byte {
+	return bytes.Join([][]byte{entryPrefix, hash}, []byte{})
+}
@@ -0,0 +1,331 @@
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+	"github.com/herlon214/ipfs-blockchain/pkg/chain"
+	"github.com/ipfs/go-cid"
+)
+
+const (
+	// maxPendingTargets bounds the targets waiting for a sync, further
+	// requests are dropped until the syncer catches up
+	maxPendingTargets = 16
+	// fetchTimeout bounds the fetch of each block
+	fetchTimeout = 30 * time.Second
+)
+
+var (
+	ErrGenesisMismatch = errors.New("peer chain does not share the local genesis block")
+	ErrUnexpectedBlock = errors.New("fetched block does not match the expected one")
+	ErrInvalidHeader   = errors.New("fetched block header is invalid")
+	ErrLighterBranch   = errors.New("peer branch does not have more work than the best chain")
+)
+
+// Fetcher fetches blocks from the network.
+type Fetcher interface {
+	// GetBlock fetches the block with the given hash and the CID of its
+	// parent
+	GetBlock(ctx context.Context, c cid.Cid, hash []byte) (*block.Block, cid.Cid, error)
+	// SetBlockCID records the CID of a fetched block once it is connected
+	SetBlockCID(hash []byte, c cid.Cid)
+}
+
+// Target is a chain tip advertised by a peer.
+type Target struct {
+	Hash   []byte
+	Height uint64
+	CID    string
+}
+
+// Syncer downloads the blocks missing to reach the tips advertised by other
+// peers and connects them to the chain. The blocks fetched and the target are
+// kept in the chain database, so a sync interrupted by a restart resumes
+// where it stopped.
+type Syncer struct {
+	Chain   *chain.Chain
+	Fetcher Fetcher
+	// OnProgress, when set, is called every time a block is fetched or
+	// connected
+	OnProgress func(p Progress)
+
+	mx sync.Mutex
+	// pending holds the requested targets by hex encoded hash
+	pending  map[string]Target
+	wake     chan struct{}
+	progress Progress
+	started  time.Time
+}
+
+func New(c *chain.Chain, f Fetcher) *Syncer {
+	return &Syncer{
+		Chain:   c,
+		Fetcher: f,
+		pending: make(map[string]Target),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Request schedules a sync to the given target. The height advertised by a
+// peer says nothing about the work of its branch, so every target is synced
+// until maxPendingTargets are waiting.
+func (s *Syncer) Request(t Target) {
+	s.mx.Lock()
+	if len(s.pending) < maxPendingTargets {
+		s.pending[hex.EncodeToString(t.Hash)] = t
+	}
+	s.mx.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run resumes the sync interrupted by the last shutdown, if any, and then
+// syncs to the requested targets until ctx is done.
+func (s *Syncer) Run(ctx context.Context) error {
+	t, ok, err := s.loadTarget()
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		log.Printf("Resuming sync to block %x at height %d\n", t.Hash, t.Height)
+
+		if err := s.Sync(ctx, t); err != nil {
+			log.Printf("Sync to block %x failed: %s\n", t.Hash, err)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.wake:
+		}
+
+		s.mx.Lock()
+		targets := make([]Target, 0, len(s.pending))
+		for key, t := range s.pending {
+			targets = append(targets, t)
+			delete(s.pending, key)
+		}
+		s.mx.Unlock()
+
+		for _, t := range targets {
+			if err := s.Sync(ctx, t); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+
+				log.Printf("Sync to block %x failed: %s\n", t.Hash, err)
+			}
+		}
+	}
+}
+
+// Sync fetches the blocks from the target back to the first block known
+// locally, checking the proof of work and the height of each one on the
+// way, and connects them from the oldest one when their branch has more
+// work than the best chain, which reorganizes the chain when the branch
+// forks from it. Any block failing the validation aborts the sync and drops
+// the blocks fetched for it.
+func (s *Syncer) Sync(ctx context.Context, t Target) error {
+	if s.Chain.HasBlock(t.Hash) {
+		return s.clear()
+	}
+
+	tip, err := s.Chain.Tip()
+	if err != nil {
+		return err
+	}
+
+	if err := s.saveTarget(t); err != nil {
+		return err
+	}
+
+	s.start(tip.Height, t.Height)
+
+	entries, err := s.fetch(ctx, t, tip)
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+
+		if errors.Is(err, ErrGenesisMismatch) || errors.Is(err, ErrUnexpectedBlock) ||
+			errors.Is(err, ErrInvalidHeader) || errors.Is(err, chain.ErrReorgTooDeep) {
+			return s.abort(err)
+		}
+
+		return err
+	}
+
+	oldest := entries[len(entries)-1].Block
+
+	work, err := s.Chain.Work(oldest.PrevHash)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		work.Add(work, chain.BlockWork(e.Block.Bits))
+	}
+
+	if work.Cmp(tip.Work) <= 0 {
+		return s.abort(fmt.Errorf("%w: block %x at height %d", ErrLighterBranch, t.Hash, t.Height))
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		b := entries[i].Block
+
+		if err := s.Chain.ConnectBlock(b); err != nil {
+			return s.abort(fmt.Errorf("block %x at height %d: %w", b.Hash, b.Height, err))
+		}
+
+		if c, err := cid.Decode(entries[i].CID); err == nil {
+			s.Fetcher.SetBlockCID(b.Hash, c)
+		}
+
+		if err := s.deleteEntry(b.Hash); err != nil {
+			return err
+		}
+
+		s.update(func(p *Progress) {
+			p.Connected++
+			p.Height = b.Height
+		})
+	}
+
+	return s.clear()
+}
+
+// fetch walks the parent links from the target until reaching a block
+// already in the chain, returning the missing blocks newest first. Blocks
+// fetched by an interrupted sync are loaded from the database. The walk
+// stops at the first block whose header is invalid and once it goes deeper
+// than a reorganization of the best chain ending at tip can go.
+func (s *Syncer) fetch(ctx context.Context, t Target, tip chain.Tip) ([]*entry, error) {
+	var entries []*entry
+
+	hash, blockCid, height := t.Hash, t.CID, t.Height
+
+	for !s.Chain.HasBlock(hash) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// The fork point is below this block, a branch forking there
+		// cannot replace the best chain
+		if height+s.Chain.Params.MaxReorgDepth <= tip.Height {
+			return nil, fmt.Errorf("%w: block %x at height %d is not known", chain.ErrReorgTooDeep, hash, height)
+		}
+
+		e, ok, err := s.loadEntry(hash)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			e, err = s.fetchEntry(ctx, hash, blockCid)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if err := s.checkHeader(e.Block, hash, height); err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			if err := s.saveEntry(e); err != nil {
+				return nil, err
+			}
+		}
+
+		s.update(func(p *Progress) {
+			p.Fetched++
+		})
+
+		entries = append(entries, e)
+
+		if e.Block.Height == 0 {
+			return nil, ErrGenesisMismatch
+		}
+
+		hash, blockCid, height = e.Block.PrevHash, e.ParentCID, e.Block.Height-1
+	}
+
+	return entries, nil
+}
+
+func (s *Syncer) fetchEntry(ctx context.Context, hash []byte, blockCid string) (*entry, error) {
+	c, err := cid.Decode(blockCid)
+	if err != nil {
+		return nil, fmt.Errorf("block %x: %w", hash, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	b, parentCid, err := s.Fetcher.GetBlock(ctx, c, hash)
+	if err != nil {
+		return nil, fmt.Errorf("block %x: %w", hash, err)
+	}
+
+	e := &entry{Block: b, CID: blockCid}
+
+	if b.Height > 0 {
+		if !parentCid.Defined() {
+			return nil, fmt.Errorf("block %x: parent CID is unknown", hash)
+		}
+
+		e.ParentCID = parentCid.String()
+	}
+
+	return e, nil
+}
+
+// checkHeader checks that b is the block expected at this step of the walk
+// and that its hash meets the difficulty it claims, within the bounds of
+// the network. The difficulty itself is checked when the block is
+// connected, once its parents are known.
+func (s *Syncer) checkHeader(b *block.Block, hash []byte, height uint64) error {
+	if !bytes.Equal(b.Hash, hash) || b.Height != height {
+		return fmt.Errorf("%w: got block %x at height %d, expected %x at height %d", ErrUnexpectedBlock, b.Hash, b.Height, hash, height)
+	}
+
+	p := s.Chain.Params
+	if b.Bits < p.MinBits || b.Bits > p.MaxBits {
+		return fmt.Errorf("%w: block %x has %d bits", ErrInvalidHeader, hash, b.Bits)
+	}
+
+	headerHash := sha256.Sum256(b.Header.Bytes())
+	if !bytes.Equal(headerHash[:], hash) || !block.NewProof(b).Validate() {
+		return fmt.Errorf("%w: block %x does not have a valid proof of work", ErrInvalidHeader, hash)
+	}
+
+	return nil
+}
+
+// abort drops the sync state after a failure caused by the peer chain.
+func (s *Syncer) abort(err error) error {
+	if clearErr := s.clear(); clearErr != nil {
+		return clearErr
+	}
+
+	return err
+}
@@ -0,0 +1,490 @@
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+	"github.com/herlon214/ipfs-blockchain/pkg/chain"
+	"github.com/herlon214/ipfs-blockchain/pkg/config"
+	"github.com/herlon214/ipfs-blockchain/pkg/params"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// genesisTime is the timestamp of the genesis block of the test chains,
+// the blocks mined on top of it are spaced from there
+const genesisTime = 1600000000
+
+const minerAddress = "1Ly731HQz58okpTBJZ5X36tndk7rp8RcVB"
+
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.DataDir = t.TempDir()
+	cfg.Network = params.Test.Name
+	cfg.Genesis = config.Genesis{
+		Address:   minerAddress,
+		Timestamp: genesisTime,
+		Message:   "sync test genesis",
+	}
+
+	return cfg
+}
+
+func openChain(t *testing.T, cfg *config.Config) *chain.Chain {
+	t.Helper()
+
+	c, err := chain.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		c.Database.Close()
+	})
+
+	return c
+}
+
+// mine mines n blocks on top of the best chain of c, spacing their
+// timestamps by the given number of seconds. The test network raises the
+// difficulty of fast blocks every RetargetInterval blocks, so fast branches
+// gather more work than slow ones of the same length.
+func mine(t *testing.T, c *chain.Chain, n int, spacing int64) []*block.Block {
+	t.Helper()
+
+	var blocks []*block.Block
+
+	for i := 0; i < n; i++ {
+		tip, err := c.GetBlock(c.LastHash())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		coinbaseTx, err := transaction.CoinBase(minerAddress, fmt.Sprintf("block %d", tip.Height+1), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := c.NewBlockTemplate([]*transaction.Transaction{coinbaseTx})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b.Timestamp = tip.Timestamp + spacing
+		if err := b.DeriveHash(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := c.ConnectBlock(b); err != nil {
+			t.Fatal(err)
+		}
+
+		blocks = append(blocks, b)
+	}
+
+	return blocks
+}
+
+func blockCID(t *testing.T, hash []byte) cid.Cid {
+	t.Helper()
+
+	sum, err := mh.Sum(hash, mh.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cid.NewCidV1(cid.DagCBOR, sum)
+}
+
+// fakeFetcher serves the blocks of a remote chain under CIDs derived from
+// their hash.
+type fakeFetcher struct {
+	mx      sync.Mutex
+	blocks  map[string]*block.Block
+	parents map[string]cid.Cid
+	// fetched holds the heights of the blocks fetched, in order
+	fetched []uint64
+	// recorded holds the CIDs given to SetBlockCID
+	recorded map[string]cid.Cid
+	// change, when set, can replace the block served or fail its fetch
+	change func(b *block.Block) (*block.Block, error)
+}
+
+func newFakeFetcher(t *testing.T, remote *chain.Chain) *fakeFetcher {
+	t.Helper()
+
+	f := &fakeFetcher{
+		blocks:   make(map[string]*block.Block),
+		parents:  make(map[string]cid.Cid),
+		recorded: make(map[string]cid.Cid),
+	}
+
+	it := remote.Iterator()
+	for {
+		b, err := it.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if b == nil {
+			break
+		}
+
+		c := blockCID(t, b.Hash).String()
+		f.blocks[c] = b
+
+		if b.Height > 0 {
+			f.parents[c] = blockCID(t, b.PrevHash)
+		}
+	}
+
+	return f
+}
+
+func (f *fakeFetcher) GetBlock(ctx context.Context, c cid.Cid, hash []byte) (*block.Block, cid.Cid, error) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	b, ok := f.blocks[c.String()]
+	if !ok {
+		return nil, cid.Undef, fmt.Errorf("no block under %s", c)
+	}
+
+	f.fetched = append(f.fetched, b.Height)
+
+	if f.change != nil {
+		var err error
+		if b, err = f.change(b); err != nil {
+			return nil, cid.Undef, err
+		}
+	}
+
+	return b, f.parents[c.String()], nil
+}
+
+func (f *fakeFetcher) SetBlockCID(hash []byte, c cid.Cid) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	f.recorded[string(hash)] = c
+}
+
+func (f *fakeFetcher) fetchedHeights() []uint64 {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	return append([]uint64{}, f.fetched...)
+}
+
+func target(t *testing.T, b *block.Block) Target {
+	return Target{Hash: b.Hash, Height: b.Height, CID: blockCID(t, b.Hash).String()}
+}
+
+func tipOf(t *testing.T, c *chain.Chain) chain.Tip {
+	t.Helper()
+
+	tip, err := c.Tip()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tip
+}
+
+// assertNoState fails unless the sync target and fetched blocks were
+// dropped.
+func assertNoState(t *testing.T, s *Syncer) {
+	t.Helper()
+
+	if _, ok, err := s.loadTarget(); err != nil || ok {
+		t.Fatalf("sync target kept after the sync: %v", err)
+	}
+}
+
+func TestSyncConnectsMissingBlocks(t *testing.T) {
+	remote := openChain(t, testConfig(t))
+	local := openChain(t, testConfig(t))
+
+	blocks := mine(t, remote, 4, 1)
+	tip := blocks[len(blocks)-1]
+
+	f := newFakeFetcher(t, remote)
+	s := New(local, f)
+
+	if err := s.Sync(context.Background(), target(t, tip)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := tipOf(t, local); !bytes.Equal(got.Hash, tip.Hash) {
+		t.Fatalf("local tip %x at height %d, want %x", got.Hash, got.Height, tip.Hash)
+	}
+
+	for _, b := range blocks {
+		if c, ok := f.recorded[string(b.Hash)]; !ok || c != blockCID(t, b.Hash) {
+			t.Errorf("CID of block %d recorded as %s, %v", b.Height, c, ok)
+		}
+	}
+
+	if p := s.Progress(); p.Connected != len(blocks) || p.Height != tip.Height {
+		t.Errorf("Progress() = %+v", p)
+	}
+
+	assertNoState(t, s)
+}
+
+func TestSyncFollowsCumulativeWork(t *testing.T) {
+	tests := []struct {
+		name string
+		// blocks and spacing of the local and remote branches, both
+		// forking from the genesis block
+		local, localSpacing   int
+		remote, remoteSpacing int
+		switched              bool
+	}{
+		// Fast blocks raise the difficulty of the blocks after the fifth
+		{"heavier shorter branch", 7, 10, 6, 0, true},
+		{"lighter longer branch", 6, 0, 7, 10, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := openChain(t, testConfig(t))
+			local := openChain(t, testConfig(t))
+
+			localBlocks := mine(t, local, tt.local, int64(tt.localSpacing))
+			remoteBlocks := mine(t, remote, tt.remote, int64(tt.remoteSpacing))
+			remoteTip := remoteBlocks[len(remoteBlocks)-1]
+			localTip := localBlocks[len(localBlocks)-1]
+
+			remoteWork, localWork := tipOf(t, remote).Work, tipOf(t, local).Work
+			if (remoteWork.Cmp(localWork) > 0) != tt.switched {
+				t.Fatalf("remote work %s, local work %s", remoteWork, localWork)
+			}
+
+			s := New(local, newFakeFetcher(t, remote))
+			err := s.Sync(context.Background(), target(t, remoteTip))
+
+			want := localTip
+			if tt.switched {
+				want = remoteTip
+
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else if !errors.Is(err, ErrLighterBranch) {
+				t.Fatalf("Sync() error = %v, want %v", err, ErrLighterBranch)
+			}
+
+			if got := tipOf(t, local); !bytes.Equal(got.Hash, want.Hash) {
+				t.Fatalf("local tip %x at height %d, want %x at height %d", got.Hash, got.Height, want.Hash, want.Height)
+			}
+
+			assertNoState(t, s)
+		})
+	}
+}
+
+func TestSyncStopsAtInvalidHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(b *block.Block) *block.Block
+		match  error
+	}{
+		{
+			name: "proof of work",
+			change: func(b *block.Block) *block.Block {
+				b.Nonce++
+				return b
+			},
+			match: ErrInvalidHeader,
+		},
+		{
+			name: "difficulty above the network maximum",
+			change: func(b *block.Block) *block.Block {
+				b.Bits = params.Test.MaxBits + 1
+				return b
+			},
+			match: ErrInvalidHeader,
+		},
+		{
+			name: "height",
+			change: func(b *block.Block) *block.Block {
+				b.Height += 10
+				return b
+			},
+			match: ErrUnexpectedBlock,
+		},
+		{
+			name: "other block",
+			change: func(b *block.Block) *block.Block {
+				b.Hash = []byte("other hash")
+				return b
+			},
+			match: ErrUnexpectedBlock,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := openChain(t, testConfig(t))
+			local := openChain(t, testConfig(t))
+
+			blocks := mine(t, remote, 5, 1)
+			before := tipOf(t, local)
+
+			f := newFakeFetcher(t, remote)
+			f.change = func(b *block.Block) (*block.Block, error) {
+				if b.Height != 3 {
+					return b, nil
+				}
+
+				changed := *b
+				return tt.change(&changed), nil
+			}
+
+			s := New(local, f)
+
+			err := s.Sync(context.Background(), target(t, blocks[len(blocks)-1]))
+			if !errors.Is(err, tt.match) {
+				t.Fatalf("Sync() error = %v, want %v", err, tt.match)
+			}
+
+			// The walk stops at the invalid block, its parents are never
+			// fetched
+			if got := f.fetchedHeights(); fmt.Sprint(got) != fmt.Sprint([]uint64{5, 4, 3}) {
+				t.Fatalf("fetched heights %v, want [5 4 3]", got)
+			}
+
+			if after := tipOf(t, local); !bytes.Equal(after.Hash, before.Hash) {
+				t.Fatalf("local tip moved to %x", after.Hash)
+			}
+
+			assertNoState(t, s)
+		})
+	}
+}
+
+func TestSyncStopsBelowReorgDepth(t *testing.T) {
+	remote := openChain(t, testConfig(t))
+	local := openChain(t, testConfig(t))
+
+	depth := int(params.Test.MaxReorgDepth)
+
+	mine(t, local, depth+2, 1)
+	blocks := mine(t, remote, 3, 0)
+
+	f := newFakeFetcher(t, remote)
+	s := New(local, f)
+
+	err := s.Sync(context.Background(), target(t, blocks[len(blocks)-1]))
+	if !errors.Is(err, chain.ErrReorgTooDeep) {
+		t.Fatalf("Sync() error = %v, want %v", err, chain.ErrReorgTooDeep)
+	}
+
+	// The block at height 2 would disconnect more than MaxReorgDepth blocks
+	if got := f.fetchedHeights(); fmt.Sprint(got) != fmt.Sprint([]uint64{3}) {
+		t.Fatalf("fetched heights %v, want [3]", got)
+	}
+
+	assertNoState(t, s)
+}
+
+func TestSyncResumesAfterRestart(t *testing.T) {
+	remote := openChain(t, testConfig(t))
+	blocks := mine(t, remote, 4, 1)
+	tip := blocks[len(blocks)-1]
+
+	cfg := testConfig(t)
+	local, err := chain.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first sync is interrupted while fetching the block at height 1
+	f := newFakeFetcher(t, remote)
+	f.change = func(b *block.Block) (*block.Block, error) {
+		if b.Height == 1 {
+			return nil, errors.New("network down")
+		}
+
+		return b, nil
+	}
+
+	if err := New(local, f).Sync(context.Background(), target(t, tip)); err == nil {
+		t.Fatal("Sync() succeeded without the block at height 1")
+	}
+
+	if err := local.Database.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	local = openChain(t, cfg)
+
+	f = newFakeFetcher(t, remote)
+	s := New(local, f)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() {
+		done <- s.Run(ctx)
+	}()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !local.HasBlock(tip.Hash) {
+		if time.Now().After(deadline) {
+			cancel()
+			t.Fatal("sync did not resume after the restart")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	// Only the block missing from the interrupted sync is fetched again
+	if got := f.fetchedHeights(); fmt.Sprint(got) != fmt.Sprint([]uint64{1}) {
+		t.Fatalf("fetched heights %v after the restart, want [1]", got)
+	}
+
+	if got := tipOf(t, local); !bytes.Equal(got.Hash, tip.Hash) {
+		t.Fatalf("local tip %x, want %x", got.Hash, tip.Hash)
+	}
+
+	assertNoState(t, s)
+}
+
+func TestRequestKeepsEveryTarget(t *testing.T) {
+	s := New(nil, nil)
+
+	// A shorter target may have more work, it is not replaced by a higher
+	// one
+	s.Request(Target{Hash: []byte("short"), Height: 2})
+	s.Request(Target{Hash: []byte("long"), Height: 9})
+
+	for i := 0; i < 2*maxPendingTargets; i++ {
+		s.Request(Target{Hash: []byte(fmt.Sprint(i)), Height: uint64(i)})
+	}
+
+	if len(s.pending) != maxPendingTargets {
+		t.Fatalf("%d targets pending, want %d", len(s.pending), maxPendingTargets)
+	}
+
+	for _, hash := range []string{"short", "long"} {
+		if _, ok := s.pending[fmt.Sprintf("%x", hash)]; !ok {
+			t.Errorf("target %s dropped", hash)
+		}
+	}
+}
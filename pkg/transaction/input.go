@@ -1,17 +1,23 @@
 package transaction
 
-import "fmt"
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/wallets"
+)
 
 type Input struct {
-	ID  []byte
-	Out int
-	Sig string
+	ID        []byte
+	Out       int
+	Signature []byte
+	PubKey    []byte
 }
 
 func (in *Input) String() string {
-	return fmt.Sprintf("Input: %x %d coins from %s", in.ID, in.Out, in.Sig)
+	return fmt.Sprintf("Input: output %d of %x signed by %x", in.Out, in.ID, in.PubKey)
 }
 
-func (in *Input) CanUnlock(data string) bool {
-	return in.Sig == data
+func (in *Input) UsesKey(pubKeyHash []byte) bool {
+	return bytes.Equal(wallets.HashPubKey(in.PubKey), pubKeyHash)
 }
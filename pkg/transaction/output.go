@@ -1,16 +1,43 @@
 package transaction
 
-import "fmt"
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/wallets"
+)
 
 type Output struct {
-	Value  int
-	PubKey string
+	Value      int
+	PubKeyHash []byte
+}
+
+func NewOutput(value int, address string) (*Output, error) {
+	out := &Output{Value: value}
+
+	if err := out.Lock([]byte(address)); err != nil {
+		return nil, err
+	}
+
+	return out, nil
 }
 
 func (out *Output) String() string {
-	return fmt.Sprintf("Output: %d to %s", out.Value, out.PubKey)
+	return fmt.Sprintf("Output: %d to %s", out.Value, wallets.EncodeAddress(out.PubKeyHash))
+}
+
+// Lock locks the output to the public key hash encoded in the given address.
+func (out *Output) Lock(address []byte) error {
+	pubKeyHash, err := wallets.DecodeAddress(address)
+	if err != nil {
+		return err
+	}
+
+	out.PubKeyHash = pubKeyHash
+
+	return nil
 }
 
-func (out *Output) CanBeUnlocked(data string) bool {
-	return out.PubKey == data
+func (out *Output) IsLockedWithKey(pubKeyHash []byte) bool {
+	return bytes.Equal(out.PubKeyHash, pubKeyHash)
 }
@@ -2,18 +2,28 @@ package transaction
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/gob"
+	"encoding/hex"
 	"fmt"
+	"math/big"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/wallets"
 )
 
+// Reward is the amount of coins created by each coinbase transaction.
+const Reward = 100
+
 type Transaction struct {
 	ID      []byte
 	Inputs  []Input
 	Outputs []Output
 }
 
-func New(inputs []Input, outputs []Output) *Transaction {
+func New(inputs []Input, outputs []Output) (*Transaction, error) {
 	tx := Transaction{
 		Inputs:  inputs,
 		Outputs: outputs,
@@ -21,10 +31,10 @@ func New(inputs []Input, outputs []Output) *Transaction {
 
 	err := tx.SetId()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	return &tx
+	return &tx, nil
 }
 
 func (tx *Transaction) SetId() error {
@@ -43,6 +53,183 @@ func (tx *Transaction) SetId() error {
 	return nil
 }
 
+func (tx *Transaction) Serialize() ([]byte, error) {
+	var res bytes.Buffer
+
+	encoder := gob.NewEncoder(&res)
+	err := encoder.Encode(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Bytes(), nil
+}
+
+func Deserialize(data []byte) (*Transaction, error) {
+	var tx Transaction
+
+	decoder := gob.NewDecoder(bytes.NewReader(data))
+	err := decoder.Decode(&tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tx, nil
+}
+
+// ValidID reports whether the transaction ID matches its content. The ID is
+// set before the inputs are signed, so signatures are left out of it.
+func (tx *Transaction) ValidID() bool {
+	txCopy := Transaction{
+		Outputs: tx.Outputs,
+	}
+
+	for _, in := range tx.Inputs {
+		txCopy.Inputs = append(txCopy.Inputs, Input{ID: in.ID, Out: in.Out, PubKey: in.PubKey})
+	}
+
+	if err := txCopy.SetId(); err != nil {
+		return false
+	}
+
+	return bytes.Equal(txCopy.ID, tx.ID)
+}
+
+// Hash returns the sha256 of the transaction encoded without its ID.
+func (tx *Transaction) Hash() ([]byte, error) {
+	var encoded bytes.Buffer
+
+	txCopy := *tx
+	txCopy.ID = []byte{}
+
+	encoder := gob.NewEncoder(&encoded)
+	err := encoder.Encode(txCopy)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(encoded.Bytes())
+
+	return hash[:], nil
+}
+
+// TrimmedCopy returns a copy of the transaction without the inputs
+// signatures and public keys, which is the payload that gets signed.
+func (tx *Transaction) TrimmedCopy() Transaction {
+	var inputs []Input
+	var outputs []Output
+
+	for _, in := range tx.Inputs {
+		inputs = append(inputs, Input{ID: in.ID, Out: in.Out})
+	}
+
+	outputs = append(outputs, tx.Outputs...)
+
+	return Transaction{
+		ID:      tx.ID,
+		Inputs:  inputs,
+		Outputs: outputs,
+	}
+}
+
+// Sign signs every input with the given private key. prevTxs must contain
+// the transactions referenced by the inputs keyed by their hex encoded ID.
+func (tx *Transaction) Sign(privKey *ecdsa.PrivateKey, prevTxs map[string]Transaction) error {
+	if tx.IsCoinBase() {
+		return nil
+	}
+
+	txCopy := tx.TrimmedCopy()
+
+	for idx, in := range txCopy.Inputs {
+		prevTx, ok := prevTxs[hex.EncodeToString(in.ID)]
+		if !ok || in.Out < 0 || in.Out >= len(prevTx.Outputs) {
+			return fmt.Errorf("previous output %x:%d not found", in.ID, in.Out)
+		}
+
+		hash, err := txCopy.signingHash(idx, prevTx.Outputs[in.Out])
+		if err != nil {
+			return err
+		}
+
+		r, s, err := ecdsa.Sign(rand.Reader, privKey, hash)
+		if err != nil {
+			return err
+		}
+
+		size := (privKey.Curve.Params().BitSize + 7) / 8
+		signature := make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+
+		tx.Inputs[idx].Signature = signature
+	}
+
+	return nil
+}
+
+// Verify checks the signature of every input against the outputs they
+// spend. prevTxs must contain the referenced transactions keyed by their hex
+// encoded ID.
+func (tx *Transaction) Verify(prevTxs map[string]Transaction) bool {
+	if tx.IsCoinBase() {
+		return true
+	}
+
+	txCopy := tx.TrimmedCopy()
+	curve := elliptic.P256()
+	size := (curve.Params().BitSize + 7) / 8
+
+	for idx, in := range tx.Inputs {
+		prevTx, ok := prevTxs[hex.EncodeToString(in.ID)]
+		if !ok || in.Out < 0 || in.Out >= len(prevTx.Outputs) {
+			return false
+		}
+
+		if len(in.Signature) != 2*size || len(in.PubKey) != 2*size {
+			return false
+		}
+
+		if !prevTx.Outputs[in.Out].IsLockedWithKey(wallets.HashPubKey(in.PubKey)) {
+			return false
+		}
+
+		hash, err := txCopy.signingHash(idx, prevTx.Outputs[in.Out])
+		if err != nil {
+			return false
+		}
+
+		r := new(big.Int).SetBytes(in.Signature[:size])
+		s := new(big.Int).SetBytes(in.Signature[size:])
+
+		pubKey := ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(in.PubKey[:size]),
+			Y:     new(big.Int).SetBytes(in.PubKey[size:]),
+		}
+		if !curve.IsOnCurve(pubKey.X, pubKey.Y) {
+			return false
+		}
+
+		if !ecdsa.Verify(&pubKey, hash, r, s) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// signingHash returns the hash signed for the input at idx, which commits to
+// the output being spent by placing its lock in the input public key.
+func (tx *Transaction) signingHash(idx int, prevOut Output) ([]byte, error) {
+	tx.Inputs[idx].PubKey = prevOut.PubKeyHash
+	defer func() {
+		tx.Inputs[idx].PubKey = nil
+	}()
+
+	return tx.Hash()
+}
+
 func (tx *Transaction) String() string {
 	result := fmt.Sprintf("%x\n", tx.ID)
 
@@ -65,20 +252,28 @@ func (tx *Transaction) IsCoinBase() bool {
 	return len(tx.Inputs) == 1 && len(tx.Inputs[0].ID) == 0 && tx.Inputs[0].Out == -1
 }
 
-func CoinBase(to string, data string) *Transaction {
+// CoinBase creates the transaction rewarding the miner of a block with the
+// block reward plus the fees of the transactions it includes.
+func CoinBase(to string, data string, fees int) (*Transaction, error) {
 	if data == "" {
-		data = fmt.Sprintf("Coins to %s", to)
+		// Random data keeps coinbase transactions to the same address unique
+		randData := make([]byte, 24)
+		if _, err := rand.Read(randData); err != nil {
+			return nil, err
+		}
+
+		data = fmt.Sprintf("Coins to %s %x", to, randData)
 	}
 
 	txin := Input{
-		ID:  []byte{},
-		Out: -1,
-		Sig: data,
+		ID:     []byte{},
+		Out:    -1,
+		PubKey: []byte(data),
 	}
-	txout := Output{
-		Value:  100,
-		PubKey: to,
+	txout, err := NewOutput(Reward+fees, to)
+	if err != nil {
+		return nil, err
 	}
 
-	return New([]Input{txin}, []Output{txout})
+	return New([]Input{txin}, []Output{*txout})
 }
@@ -0,0 +1,56 @@
+package transaction
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	ErrNoInputs      = errors.New("transaction spends no output")
+	ErrNoOutputs     = errors.New("transaction pays no output")
+	ErrInvalidValue  = errors.New("output value must be positive")
+	ErrValueOverflow = errors.New("value overflow")
+)
+
+// CheckValues checks the rules a transaction follows on its own: it spends
+// at least one output unless it is a coinbase, it pays at least one output
+// and every output has a positive value. It returns the value of the
+// outputs.
+func (tx *Transaction) CheckValues() (int, error) {
+	if !tx.IsCoinBase() && len(tx.Inputs) == 0 {
+		return 0, ErrNoInputs
+	}
+
+	if len(tx.Outputs) == 0 {
+		return 0, ErrNoOutputs
+	}
+
+	total := 0
+	for idx, out := range tx.Outputs {
+		if out.Value <= 0 {
+			return 0, fmt.Errorf("%w: output %d has value %d", ErrInvalidValue, idx, out.Value)
+		}
+
+		var err error
+		total, err = AddValues(total, out.Value)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return total, nil
+}
+
+// AddValues adds two non negative values, failing instead of overflowing.
+func AddValues(a int, b int) (int, error) {
+	if a < 0 || b < 0 {
+		return 0, fmt.Errorf("%w: adding %d and %d", ErrInvalidValue, a, b)
+	}
+
+	if a > math.MaxInt-b {
+		return 0, fmt.Errorf("%w: adding %d and %d", ErrValueOverflow, a, b)
+	}
+
+	return a + b, nil
+}
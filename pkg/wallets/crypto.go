@@ -0,0 +1,219 @@
+package wallets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Encrypted wallet files are a JSON envelope holding the scrypt parameters
+// deriving the key from the passphrase and the AES-256-GCM encrypted
+// wallets. Every envelope field other than the ciphertext is authenticated
+// as additional data, so any change to the file makes decryption fail.
+const (
+	envelopeFormat  = "ipfs-blockchain-wallet"
+	envelopeVersion = 1
+	envelopeCipher  = "aes-256-gcm"
+	kdfScrypt       = "scrypt"
+
+	scryptN    = 1 << 15
+	scryptR    = 8
+	scryptP    = 1
+	scryptSalt = 16
+	// maxScryptN bounds the work a crafted file can demand
+	maxScryptN = 1 << 20
+
+	// The derived key is split in the encryption key and a key whose hash
+	// tells a wrong passphrase apart from a tampered file
+	encryptionKeyLen = 32
+	checkKeyLen      = 32
+)
+
+var (
+	ErrLocked          = errors.New("wallet file is encrypted, a passphrase is required")
+	ErrNotEncrypted    = errors.New("wallet file is not encrypted")
+	ErrWrongPassphrase = errors.New("wrong wallet passphrase")
+	ErrTampered        = errors.New("wallet file was modified or is corrupted")
+)
+
+type envelope struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	KDF        kdfParams `json:"kdf"`
+	Cipher     string    `json:"cipher"`
+	Nonce      string    `json:"nonce"`
+	Check      string    `json:"check"`
+	Ciphertext string    `json:"ciphertext,omitempty"`
+}
+
+type kdfParams struct {
+	Name string `json:"name"`
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+	Salt string `json:"salt"`
+}
+
+// parseEnvelope reports whether data is an encrypted wallet file.
+func parseEnvelope(data []byte) (*envelope, bool) {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil || env.Format != envelopeFormat {
+		return nil, false
+	}
+
+	return &env, true
+}
+
+// encrypt seals plaintext in an envelope with a key derived from the
+// passphrase and a new random salt.
+func encrypt(plaintext []byte, passphrase []byte) ([]byte, error) {
+	salt := make([]byte, scryptSalt)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	env := &envelope{
+		Format:  envelopeFormat,
+		Version: envelopeVersion,
+		KDF: kdfParams{
+			Name: kdfScrypt,
+			N:    scryptN,
+			R:    scryptR,
+			P:    scryptP,
+			Salt: hex.EncodeToString(salt),
+		},
+		Cipher: envelopeCipher,
+	}
+
+	encryptionKey, checkKey, err := deriveKeys(passphrase, salt, env.KDF)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	check := sha256.Sum256(checkKey)
+	env.Nonce = hex.EncodeToString(nonce)
+	env.Check = hex.EncodeToString(check[:])
+
+	ad, err := json.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+
+	env.Ciphertext = hex.EncodeToString(aead.Seal(nil, nonce, plaintext, ad))
+
+	return json.MarshalIndent(env, "", "  ")
+}
+
+// decrypt opens the envelope, returning ErrWrongPassphrase when the
+// passphrase does not match and ErrTampered when the file was changed.
+func decrypt(env *envelope, passphrase []byte) ([]byte, error) {
+	if env.Version != envelopeVersion || env.Cipher != envelopeCipher || env.KDF.Name != kdfScrypt {
+		return nil, fmt.Errorf("%w: unsupported version %d with %s and %s", ErrTampered, env.Version, env.KDF.Name, env.Cipher)
+	}
+
+	if err := env.KDF.validate(); err != nil {
+		return nil, err
+	}
+
+	salt, err := hex.DecodeString(env.KDF.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: salt: %s", ErrTampered, err)
+	}
+
+	nonce, err := hex.DecodeString(env.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: nonce: %s", ErrTampered, err)
+	}
+
+	check, err := hex.DecodeString(env.Check)
+	if err != nil {
+		return nil, fmt.Errorf("%w: check: %s", ErrTampered, err)
+	}
+
+	ciphertext, err := hex.DecodeString(env.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("%w: ciphertext: %s", ErrTampered, err)
+	}
+
+	encryptionKey, checkKey, err := deriveKeys(passphrase, salt, env.KDF)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := sha256.Sum256(checkKey)
+	if subtle.ConstantTimeCompare(expected[:], check) != 1 {
+		return nil, ErrWrongPassphrase
+	}
+
+	aead, err := newAEAD(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: nonce of %d bytes", ErrTampered, len(nonce))
+	}
+
+	header := *env
+	header.Ciphertext = ""
+
+	ad, err := json.Marshal(&header)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, ad)
+	if err != nil {
+		return nil, ErrTampered
+	}
+
+	return plaintext, nil
+}
+
+func (p kdfParams) validate() error {
+	if p.N <= 1 || p.N > maxScryptN || p.N&(p.N-1) != 0 {
+		return fmt.Errorf("%w: scrypt N %d", ErrTampered, p.N)
+	}
+
+	if p.R <= 0 || p.R > 32 || p.P <= 0 || p.P > 16 {
+		return fmt.Errorf("%w: scrypt r %d and p %d", ErrTampered, p.R, p.P)
+	}
+
+	return nil
+}
+
+func deriveKeys(passphrase []byte, salt []byte, p kdfParams) ([]byte, []byte, error) {
+	key, err := scrypt.Key(passphrase, salt, p.N, p.R, p.P, encryptionKeyLen+checkKeyLen)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key[:encryptionKeyLen], key[encryptionKeyLen:], nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
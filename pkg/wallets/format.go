@@ -0,0 +1,242 @@
+package wallets
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Wallet files hold a JSON document, wrapped in the envelope of crypto.go
+// when the file is encrypted:
+//
+//	{
+//	  "format": "ipfs-blockchain-wallet-keys",
+//	  "version": 1,
+//	  "curve": "P-256",
+//	  "seed": "<64 bytes in hex>",
+//	  "nextIndex": 1,
+//	  "keys": [
+//	    {
+//	      "address": "<base58 address>",
+//	      "path": "m/44'/1'/0'/0/0",
+//	      "privateKey": "<32 bytes in hex>"
+//	    }
+//	  ]
+//	}
+//
+// The seed and the next index are only set for deterministic wallets, the
+// path only for derived keys. Private keys are the hex encoded 32 byte big
+// endian scalar, the public key and the address follow from them and the
+// address is checked on load. Readers reject the versions they do not know.
+//
+// Earlier releases wrote the wallets with encoding/gob, Load migrates those
+// files to this format.
+const (
+	documentFormat  = "ipfs-blockchain-wallet-keys"
+	documentVersion = 1
+	documentCurve   = "P-256"
+
+	// legacyBackupSuffix names the copy of a gob file kept by the migration
+	legacyBackupSuffix = ".gob.bak"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported wallet file format")
+
+type document struct {
+	Format    string        `json:"format"`
+	Version   int           `json:"version"`
+	Curve     string        `json:"curve"`
+	Seed      string        `json:"seed,omitempty"`
+	NextIndex uint32        `json:"nextIndex,omitempty"`
+	Keys      []documentKey `json:"keys"`
+}
+
+type documentKey struct {
+	Address    string `json:"address"`
+	Path       string `json:"path,omitempty"`
+	PrivateKey string `json:"privateKey"`
+}
+
+// marshal encodes the wallets as a document, the keys sorted by path and
+// address so saving unchanged wallets gives the same file.
+func (ws *Wallets) marshal() ([]byte, error) {
+	doc := document{
+		Format:    documentFormat,
+		Version:   documentVersion,
+		Curve:     documentCurve,
+		NextIndex: ws.NextIndex,
+		Keys:      make([]documentKey, 0, len(ws.Items)),
+	}
+
+	if ws.Deterministic() {
+		doc.Seed = hex.EncodeToString(ws.Seed)
+	}
+
+	for address, wallet := range ws.Items {
+		doc.Keys = append(doc.Keys, documentKey{
+			Address:    address,
+			Path:       wallet.Path,
+			PrivateKey: hex.EncodeToString(wallet.PrivateKey.D.FillBytes(make([]byte, 32))),
+		})
+	}
+
+	sort.Slice(doc.Keys, func(i, j int) bool {
+		if doc.Keys[i].Path != doc.Keys[j].Path {
+			return comparePaths(doc.Keys[i].Path, doc.Keys[j].Path)
+		}
+
+		return doc.Keys[i].Address < doc.Keys[j].Address
+	})
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// isDocument reports whether data holds a wallet document, of any version.
+func isDocument(data []byte) bool {
+	var header struct {
+		Format string `json:"format"`
+	}
+
+	return json.Unmarshal(data, &header) == nil && header.Format == documentFormat
+}
+
+func unmarshal(data []byte) (*Wallets, error) {
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrTampered, err)
+	}
+
+	if doc.Version != documentVersion || doc.Curve != documentCurve {
+		return nil, fmt.Errorf("%w: version %d with curve %s", ErrUnsupportedFormat, doc.Version, doc.Curve)
+	}
+
+	seed, err := hex.DecodeString(doc.Seed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: seed: %s", ErrTampered, err)
+	}
+
+	ws := &Wallets{
+		Items:     make(map[string]*Wallet, len(doc.Keys)),
+		Seed:      seed,
+		NextIndex: doc.NextIndex,
+	}
+
+	for _, key := range doc.Keys {
+		d, err := hex.DecodeString(key.PrivateKey)
+		if err != nil || len(d) != 32 || !validKey(d) {
+			return nil, fmt.Errorf("%w: invalid private key of %s", ErrTampered, key.Address)
+		}
+
+		wallet := walletFromKey(newPrivateKey(d), key.Path)
+		if string(wallet.Address()) != key.Address {
+			return nil, fmt.Errorf("%w: private key does not match address %s", ErrTampered, key.Address)
+		}
+
+		ws.Items[key.Address] = wallet
+	}
+
+	if len(ws.Seed) == 0 {
+		ws.Seed = nil
+	}
+
+	return ws, nil
+}
+
+// comparePaths orders the derivation paths by their indexes, the keys
+// without path last.
+func comparePaths(a string, b string) bool {
+	if a == "" || b == "" {
+		return b == ""
+	}
+
+	ia, errA := ParsePath(a)
+	ib, errB := ParsePath(b)
+	if errA != nil || errB != nil {
+		return a < b
+	}
+
+	for i := 0; i < len(ia) && i < len(ib); i++ {
+		if ia[i] != ib[i] {
+			return ia[i] < ib[i]
+		}
+	}
+
+	return len(ia) < len(ib)
+}
+
+// legacyCurve decodes the P-256 curve stored in gob files. The files were
+// written before Go 1.20, whose curve types can no longer go through gob.
+type legacyCurve struct {
+	*elliptic.CurveParams
+}
+
+type legacyWallets struct {
+	Items     map[string]*legacyWallet
+	Seed      []byte
+	NextIndex uint32
+}
+
+type legacyWallet struct {
+	PrivateKey *ecdsa.PrivateKey
+	Path       string
+}
+
+func init() {
+	gob.RegisterName("crypto/elliptic.p256Curve", legacyCurve{})
+}
+
+// unmarshalLegacy decodes a gob wallet file of the earlier releases.
+func unmarshalLegacy(data []byte) (*Wallets, error) {
+	var legacy legacyWallets
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&legacy); err != nil {
+		return nil, err
+	}
+
+	ws := &Wallets{
+		Items:     make(map[string]*Wallet, len(legacy.Items)),
+		Seed:      legacy.Seed,
+		NextIndex: legacy.NextIndex,
+		Renamed:   make(map[string]string),
+	}
+
+	for address, item := range legacy.Items {
+		if item.PrivateKey == nil || item.PrivateKey.D == nil {
+			return nil, fmt.Errorf("missing private key of %s", address)
+		}
+
+		d := item.PrivateKey.D.Bytes()
+		if len(d) > 32 || !validKey(d) {
+			return nil, fmt.Errorf("invalid private key of %s", address)
+		}
+
+		wallet := walletFromKey(newPrivateKey(d), item.Path)
+		current := string(wallet.Address())
+
+		if current != address {
+			if legacyAddress(wallet.PrivateKey) != address {
+				return nil, fmt.Errorf("private key does not match address %s", address)
+			}
+
+			ws.Renamed[address] = current
+		}
+
+		ws.Items[current] = wallet
+	}
+
+	return ws, nil
+}
+
+// legacyAddress returns the address the first releases gave to the key,
+// which hashed the X and Y coordinates without padding them. It differs
+// from the current address when a coordinate has a leading zero byte.
+func legacyAddress(private *ecdsa.PrivateKey) string {
+	pub := append(private.PublicKey.X.Bytes(), private.PublicKey.Y.Bytes()...)
+
+	return string(EncodeAddress(HashPubKey(pub)))
+}
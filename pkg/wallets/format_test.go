@@ -0,0 +1,178 @@
+package wallets
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/gob"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// baselineWallets is the gob layout of the wallet files of the first
+// releases.
+type baselineWallets struct {
+	FilePath string
+	Items    map[string]*baselineWallet
+}
+
+type baselineWallet struct {
+	PrivateKey *ecdsa.PrivateKey
+	PublicKey  []byte
+}
+
+// generateKey returns a key whose coordinates have a leading zero byte when
+// short is set, so its legacy address differs from the current one.
+func generateKey(t *testing.T, short bool) *ecdsa.PrivateKey {
+	t.Helper()
+
+	for {
+		private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		isShort := len(private.X.Bytes()) < 32 || len(private.Y.Bytes()) < 32
+		if isShort == short {
+			return private
+		}
+	}
+}
+
+// writeBaseline writes the keys in a gob file the way the first releases
+// did, under the given addresses.
+func writeBaseline(t *testing.T, path string, keys map[string]*ecdsa.PrivateKey, passphrase []byte) []byte {
+	t.Helper()
+
+	ws := baselineWallets{FilePath: path, Items: make(map[string]*baselineWallet)}
+	for address, private := range keys {
+		legacy := *private
+		legacy.Curve = legacyCurve{elliptic.P256().Params()}
+
+		ws.Items[address] = &baselineWallet{
+			PrivateKey: &legacy,
+			PublicKey:  append(private.X.Bytes(), private.Y.Bytes()...),
+		}
+	}
+
+	var data bytes.Buffer
+	if err := gob.NewEncoder(&data).Encode(ws); err != nil {
+		t.Fatal(err)
+	}
+
+	content := data.Bytes()
+	if len(passphrase) > 0 {
+		var err error
+		content, err = encrypt(content, passphrase)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return content
+}
+
+func TestLoadMigratesGobFile(t *testing.T) {
+	for _, passphrase := range [][]byte{nil, []byte("secret")} {
+		t.Run(string(passphrase), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "wallet.dat")
+
+			full := generateKey(t, false)
+			short := generateKey(t, true)
+
+			fullAddress := string(walletFromKey(full, "").Address())
+			shortAddress := string(walletFromKey(short, "").Address())
+			shortLegacy := legacyAddress(short)
+
+			if shortLegacy == shortAddress {
+				t.Fatal("short key has the same legacy and current address")
+			}
+
+			original := writeBaseline(t, path, map[string]*ecdsa.PrivateKey{
+				legacyAddress(full): full,
+				shortLegacy:         short,
+			}, passphrase)
+
+			ws, err := Load(path, func() ([]byte, error) { return passphrase, nil })
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, address := range []string{fullAddress, shortAddress} {
+				if ws.Wallet(address) == nil {
+					t.Errorf("address %s missing after the migration", address)
+				}
+			}
+
+			if len(ws.Renamed) != 1 || ws.Renamed[shortLegacy] != shortAddress {
+				t.Errorf("Renamed = %v, want %s renamed to %s", ws.Renamed, shortLegacy, shortAddress)
+			}
+
+			if ws.Encrypted() != (len(passphrase) > 0) {
+				t.Errorf("Encrypted() = %v after the migration", ws.Encrypted())
+			}
+
+			backup, err := os.ReadFile(path + legacyBackupSuffix)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(backup, original) {
+				t.Error("backup differs from the gob file")
+			}
+
+			// The migrated file is a document, loading it again changes
+			// nothing
+			reloaded, err := Load(path, func() ([]byte, error) { return passphrase, nil })
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(reloaded.Items) != 2 || len(reloaded.Renamed) != 0 {
+				t.Errorf("reloaded %d addresses, %d renamed", len(reloaded.Items), len(reloaded.Renamed))
+			}
+		})
+	}
+}
+
+func TestLoadMigratesCurrentGobAddresses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wallet.dat")
+
+	// Files written after the public key got fixed width already use the
+	// current address
+	short := generateKey(t, true)
+	address := string(walletFromKey(short, "").Address())
+
+	writeBaseline(t, path, map[string]*ecdsa.PrivateKey{address: short}, nil)
+
+	ws, err := Load(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ws.Wallet(address) == nil || len(ws.Renamed) != 0 {
+		t.Fatalf("address %s not kept, renamed %v", address, ws.Renamed)
+	}
+}
+
+func TestLoadRejectsGobKeyOfAnotherAddress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wallet.dat")
+
+	other := string(walletFromKey(generateKey(t, false), "").Address())
+	writeBaseline(t, path, map[string]*ecdsa.PrivateKey{other: generateKey(t, false)}, nil)
+
+	if _, err := Load(path, nil); !errors.Is(err, ErrTampered) {
+		t.Fatalf("Load() error = %v, want %v", err, ErrTampered)
+	}
+
+	if _, err := os.Stat(path + legacyBackupSuffix); !os.IsNotExist(err) {
+		t.Fatalf("rejected file was migrated: %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package wallets
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Keys are derived from the seed following SLIP-10 for the P-256 curve, the
+// elliptic curve counterpart of BIP-32. With the seed 000102030405060708090a
+// 0b0c0d0e0f the master key is 612091aaa12e22dd2abef664f8a01a82cae99ad7441b7
+// ef8110424915c268bc2 and its first hardened child, m/0', is 6939694369114c6
+// 7917a182c59ddb8cafc3004e63ca5d3b84403ba8613debc0c.
+const (
+	// HardenedOffset is added to the index of hardened children, whose keys
+	// can not be derived from the parent public key
+	HardenedOffset uint32 = 0x80000000
+
+	// AccountPath is the BIP-44 path of the receiving addresses, with the
+	// coin type shared by the test networks
+	AccountPath = "m/44'/1'/0'/0"
+
+	masterKeySalt = "Nist256p1 seed"
+)
+
+var ErrInvalidPath = errors.New("invalid derivation path")
+
+// ExtendedKey is a private key along with the chain code deriving its
+// children.
+type ExtendedKey struct {
+	Key       []byte
+	ChainCode []byte
+}
+
+// NewMasterKey derives the root key of a seed.
+func NewMasterKey(seed []byte) *ExtendedKey {
+	i := hmacSHA512([]byte(masterKeySalt), seed)
+
+	// The odds of an invalid key are below 2^-127, the derivation is
+	// repeated on the result when it happens
+	for !validKey(i[:32]) {
+		i = hmacSHA512([]byte(masterKeySalt), i)
+	}
+
+	return &ExtendedKey{Key: i[:32], ChainCode: i[32:]}
+}
+
+// Child derives the child key at index, hardened from HardenedOffset on.
+func (k *ExtendedKey) Child(index uint32) *ExtendedKey {
+	n := elliptic.P256().Params().N
+
+	var data []byte
+	if index >= HardenedOffset {
+		data = append([]byte{0}, k.Key...)
+	} else {
+		x, y := elliptic.P256().ScalarBaseMult(k.Key)
+		data = elliptic.MarshalCompressed(elliptic.P256(), x, y)
+	}
+
+	data = appendIndex(data, index)
+
+	for {
+		i := hmacSHA512(k.ChainCode, data)
+
+		tweak := new(big.Int).SetBytes(i[:32])
+		if tweak.Cmp(n) < 0 {
+			child := tweak.Add(tweak, new(big.Int).SetBytes(k.Key))
+			child.Mod(child, n)
+
+			if child.Sign() != 0 {
+				return &ExtendedKey{Key: child.FillBytes(make([]byte, 32)), ChainCode: i[32:]}
+			}
+		}
+
+		data = appendIndex(append([]byte{1}, i[32:]...), index)
+	}
+}
+
+// Derive follows the path, as returned by ParsePath, from the key.
+func (k *ExtendedKey) Derive(path []uint32) *ExtendedKey {
+	for _, index := range path {
+		k = k.Child(index)
+	}
+
+	return k
+}
+
+// PrivateKey returns the ECDSA key of the extended key.
+func (k *ExtendedKey) PrivateKey() *ecdsa.PrivateKey {
+	return newPrivateKey(k.Key)
+}
+
+// ParsePath parses a path such as m/44'/1'/0'/0/3, where an apostrophe or
+// an h marks the hardened indexes.
+func ParsePath(path string) ([]uint32, error) {
+	parts := strings.Split(path, "/")
+	if parts[0] != "m" {
+		return nil, fmt.Errorf("%w: %s does not start with m", ErrInvalidPath, path)
+	}
+
+	indexes := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		offset := uint32(0)
+		if strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h") {
+			part = part[:len(part)-1]
+			offset = HardenedOffset
+		}
+
+		index, err := strconv.ParseUint(part, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %s", ErrInvalidPath, path, err)
+		}
+
+		indexes = append(indexes, uint32(index)+offset)
+	}
+
+	return indexes, nil
+}
+
+// AddressPath returns the derivation path of the receiving address at index.
+func AddressPath(index uint32) string {
+	return fmt.Sprintf("%s/%d", AccountPath, index)
+}
+
+// DeriveWallet derives the wallet at path from the seed.
+func DeriveWallet(seed []byte, path string) (*Wallet, error) {
+	indexes, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return walletFromKey(NewMasterKey(seed).Derive(indexes).PrivateKey(), path), nil
+}
+
+func appendIndex(data []byte, index uint32) []byte {
+	var buff [4]byte
+	binary.BigEndian.PutUint32(buff[:], index)
+
+	return append(data, buff[:]...)
+}
+
+func hmacSHA512(key []byte, data []byte) []byte {
+	mac := hmac.New(sha512.New, key)
+	mac.Write(data)
+
+	return mac.Sum(nil)
+}
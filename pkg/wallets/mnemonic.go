@@ -0,0 +1,140 @@
+package wallets
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Mnemonics follow BIP-39: the entropy followed by the first bits of its
+// SHA-256 checksum, split in groups of 11 bits indexing the word list.
+const (
+	// DefaultEntropyBits gives a 12 word mnemonic
+	DefaultEntropyBits = 128
+
+	seedIterations = 2048
+	seedLength     = 64
+)
+
+var ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+var wordIndex = func() map[string]int {
+	index := make(map[string]int, len(wordlist))
+	for i, word := range wordlist {
+		index[word] = i
+	}
+
+	return index
+}()
+
+// NewMnemonic returns a mnemonic encoding bits of random entropy, a multiple
+// of 32 between 128 and 256.
+func NewMnemonic(bits int) (string, error) {
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return "", fmt.Errorf("entropy of %d bits, expected a multiple of 32 between 128 and 256", bits)
+	}
+
+	entropy := make([]byte, bits/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return "", err
+	}
+
+	return EntropyToMnemonic(entropy)
+}
+
+// EntropyToMnemonic encodes the entropy, 16 to 32 bytes, as a mnemonic.
+func EntropyToMnemonic(entropy []byte) (string, error) {
+	if len(entropy) < 16 || len(entropy) > 32 || len(entropy)%4 != 0 {
+		return "", fmt.Errorf("entropy of %d bytes, expected a multiple of 4 between 16 and 32", len(entropy))
+	}
+
+	checksum := sha256.Sum256(entropy)
+	data := append(append([]byte{}, entropy...), checksum[0])
+	bits := len(entropy)*8 + len(entropy)/4
+
+	words := make([]string, 0, bits/11)
+	for i := 0; i < bits; i += 11 {
+		words = append(words, wordlist[readBits(data, i, 11)])
+	}
+
+	return strings.Join(words, " "), nil
+}
+
+// MnemonicToEntropy decodes a mnemonic, failing with ErrInvalidMnemonic on
+// unknown words or a checksum mismatch.
+func MnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, fmt.Errorf("%w: %d words, expected 12, 15, 18, 21 or 24", ErrInvalidMnemonic, len(words))
+	}
+
+	bits := len(words) * 11
+	checksumBits := bits / 33
+	data := make([]byte, (bits+7)/8)
+
+	for i, word := range words {
+		index, ok := wordIndex[strings.ToLower(word)]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown word %q", ErrInvalidMnemonic, word)
+		}
+
+		writeBits(data, i*11, 11, index)
+	}
+
+	entropy := data[:(bits-checksumBits)/8]
+	checksum := sha256.Sum256(entropy)
+
+	if readBits(data, len(entropy)*8, checksumBits) != int(checksum[0])>>(8-checksumBits) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidMnemonic)
+	}
+
+	return entropy, nil
+}
+
+// NormalizeMnemonic validates the mnemonic and returns it lower case with
+// single spaces between the words.
+func NormalizeMnemonic(mnemonic string) (string, error) {
+	if _, err := MnemonicToEntropy(mnemonic); err != nil {
+		return "", err
+	}
+
+	return strings.ToLower(strings.Join(strings.Fields(mnemonic), " ")), nil
+}
+
+// MnemonicSeed derives the 64 byte seed of a mnemonic protected by an
+// optional passphrase. Both are used as given, non ASCII passphrases must
+// be NFKD normalized by the caller.
+//
+// The mnemonic "abandon abandon abandon abandon abandon abandon abandon
+// abandon abandon abandon abandon about" with the passphrase "TREZOR" gives
+// the seed c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e5349553
+// 1f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04.
+func MnemonicSeed(mnemonic string, passphrase string) []byte {
+	return pbkdf2.Key([]byte(mnemonic), []byte("mnemonic"+passphrase), seedIterations, seedLength, sha512.New)
+}
+
+// readBits returns n bits of data starting at bit offset, most significant
+// bit first.
+func readBits(data []byte, offset int, n int) int {
+	value := 0
+	for i := offset; i < offset+n; i++ {
+		value = value<<1 | int(data[i/8]>>(7-i%8)&1)
+	}
+
+	return value
+}
+
+// writeBits sets n bits of data starting at bit offset to value.
+func writeBits(data []byte, offset int, n int, value int) {
+	for i := 0; i < n; i++ {
+		if value>>(n-1-i)&1 == 1 {
+			pos := offset + i
+			data[pos/8] |= 1 << (7 - pos%8)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package wallets
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// BIP-39 test vectors, the seeds use the passphrase "TREZOR".
+var mnemonicVectors = []struct {
+	entropy  string
+	mnemonic string
+	seed     string
+}{
+	{
+		"00000000000000000000000000000000",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+	},
+	{
+		"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+		"legal winner thank year wave sausage worth useful legal winner thank yellow",
+		"2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+	},
+	{
+		"80808080808080808080808080808080",
+		"letter advice cage absurd amount doctor acoustic avoid letter advice cage above",
+		"d71de856f81a8acc65e6fc851a38d4d7ec216fd0796d0a6827a3ad6ed5511a30fa280f12eb2e47ed2ac03b5c462a0358d18d69fe4f985ec81778c1b370b652a8",
+	},
+	{
+		"ffffffffffffffffffffffffffffffff",
+		"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+		"ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+	},
+	{
+		"000000000000000000000000000000000000000000000000",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon agent",
+		"035895f2f481b1b0f01fcf8c289c794660b289981a78f8106447707fdd9666ca06da5a9a565181599b79f53b844d8a71dd9f439c52a3d7b3e8a79c906ac845fa",
+	},
+	{
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art",
+		"bda85446c68413707090a52022edd26a1c9462295029f2e60cd7c4f2bbd3097170af7a4d73245cafa9c3cca8d561a7c3de6f5d4a10be8ed2a5e608d68f92fcc8",
+	},
+}
+
+func TestMnemonicVectors(t *testing.T) {
+	for _, tt := range mnemonicVectors {
+		t.Run(tt.entropy, func(t *testing.T) {
+			entropy, err := hex.DecodeString(tt.entropy)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mnemonic, err := EntropyToMnemonic(entropy)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if mnemonic != tt.mnemonic {
+				t.Fatalf("EntropyToMnemonic() = %q, want %q", mnemonic, tt.mnemonic)
+			}
+
+			decoded, err := MnemonicToEntropy(tt.mnemonic)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if hex.EncodeToString(decoded) != tt.entropy {
+				t.Fatalf("MnemonicToEntropy() = %x, want %s", decoded, tt.entropy)
+			}
+
+			if seed := hex.EncodeToString(MnemonicSeed(tt.mnemonic, "TREZOR")); seed != tt.seed {
+				t.Fatalf("MnemonicSeed() = %s, want %s", seed, tt.seed)
+			}
+		})
+	}
+}
+
+func TestMnemonicToEntropyErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		mnemonic string
+	}{
+		{"too short", "abandon abandon abandon"},
+		{"not a multiple of three words", strings.Repeat("abandon ", 13)},
+		{"unknown word", strings.Repeat("abandon ", 11) + "bitcoin"},
+		{"checksum mismatch", strings.Repeat("abandon ", 12)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := MnemonicToEntropy(tt.mnemonic); !errors.Is(err, ErrInvalidMnemonic) {
+				t.Fatalf("MnemonicToEntropy() error = %v, want %v", err, ErrInvalidMnemonic)
+			}
+		})
+	}
+}
+
+func TestNormalizeMnemonic(t *testing.T) {
+	got, err := NormalizeMnemonic("  Legal winner THANK year wave sausage worth useful legal winner thank\tyellow\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := mnemonicVectors[1].mnemonic; got != want {
+		t.Fatalf("NormalizeMnemonic() = %q, want %q", got, want)
+	}
+}
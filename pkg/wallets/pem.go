@@ -0,0 +1,106 @@
+package wallets
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// Key formats of ExportPEM.
+const (
+	// FormatPKCS8 is the PKCS #8 "PRIVATE KEY" block
+	FormatPKCS8 = "pkcs8"
+	// FormatSEC1 is the SEC 1 "EC PRIVATE KEY" block
+	FormatSEC1 = "sec1"
+
+	pemTypePKCS8 = "PRIVATE KEY"
+	pemTypeSEC1  = "EC PRIVATE KEY"
+)
+
+var (
+	ErrUnsupportedKey = errors.New("unsupported private key")
+	ErrWalletExists   = errors.New("address already in the wallet")
+)
+
+// ExportPEM encodes the private key of the wallet as a PEM block of the
+// given format.
+func (w *Wallet) ExportPEM(format string) ([]byte, error) {
+	var block *pem.Block
+
+	switch format {
+	case FormatPKCS8:
+		der, err := x509.MarshalPKCS8PrivateKey(w.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+
+		block = &pem.Block{Type: pemTypePKCS8, Bytes: der}
+	case FormatSEC1:
+		der, err := x509.MarshalECPrivateKey(w.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+
+		block = &pem.Block{Type: pemTypeSEC1, Bytes: der}
+	default:
+		return nil, fmt.Errorf("unknown key format %q, expected %s or %s", format, FormatPKCS8, FormatSEC1)
+	}
+
+	return pem.EncodeToMemory(block), nil
+}
+
+// ImportPEM decodes a PKCS #8 or SEC 1 PEM encoded P-256 private key.
+func ImportPEM(data []byte) (*Wallet, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM block found", ErrUnsupportedKey)
+	}
+
+	var private *ecdsa.PrivateKey
+
+	switch block.Type {
+	case pemTypePKCS8:
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedKey, err)
+		}
+
+		ecKey, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T is not an ECDSA key", ErrUnsupportedKey, key)
+		}
+
+		private = ecKey
+	case pemTypeSEC1:
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedKey, err)
+		}
+
+		private = key
+	default:
+		return nil, fmt.Errorf("%w: PEM block %q", ErrUnsupportedKey, block.Type)
+	}
+
+	if private.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("%w: curve %s, expected P-256", ErrUnsupportedKey, private.Curve.Params().Name)
+	}
+
+	return walletFromKey(newPrivateKey(private.D.FillBytes(make([]byte, 32))), ""), nil
+}
+
+// Import adds a wallet, failing with ErrWalletExists when its address is
+// already in the file.
+func (ws *Wallets) Import(wallet *Wallet) error {
+	address := string(wallet.Address())
+	if _, ok := ws.Items[address]; ok {
+		return fmt.Errorf("%w: %s", ErrWalletExists, address)
+	}
+
+	ws.Items[address] = wallet
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package wallets
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPEMRoundTrip(t *testing.T) {
+	tests := []struct {
+		format    string
+		blockType string
+	}{
+		{FormatPKCS8, "PRIVATE KEY"},
+		{FormatSEC1, "EC PRIVATE KEY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			// Short coordinates must survive the round trip too
+			for _, short := range []bool{false, true} {
+				wallet := walletFromKey(generateKey(t, short), "")
+
+				data, err := wallet.ExportPEM(tt.format)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if block, _ := pem.Decode(data); block == nil || block.Type != tt.blockType {
+					t.Fatalf("ExportPEM() = %q, want a %s block", data, tt.blockType)
+				}
+
+				imported, err := ImportPEM(data)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if string(imported.Address()) != string(wallet.Address()) {
+					t.Fatalf("imported address %s, want %s", imported.Address(), wallet.Address())
+				}
+
+				if imported.PrivateKey.D.Cmp(wallet.PrivateKey.D) != 0 {
+					t.Fatal("imported a different private key")
+				}
+			}
+		})
+	}
+}
+
+func TestExportPEMUnknownFormat(t *testing.T) {
+	wallet := walletFromKey(generateKey(t, false), "")
+
+	if _, err := wallet.ExportPEM("der"); err == nil {
+		t.Fatal("ExportPEM() accepted an unknown format")
+	}
+}
+
+func TestImportPEMRejects(t *testing.T) {
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(p384)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not PEM", []byte("not a key")},
+		{"unknown block", pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte{1}})},
+		{"corrupt PKCS8", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{1, 2, 3}})},
+		{"corrupt SEC1", pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte{1, 2, 3}})},
+		{"other curve", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ImportPEM(tt.data); !errors.Is(err, ErrUnsupportedKey) {
+				t.Fatalf("ImportPEM() error = %v, want %v", err, ErrUnsupportedKey)
+			}
+		})
+	}
+}
+
+func TestImport(t *testing.T) {
+	ws := &Wallets{Items: make(map[string]*Wallet)}
+	wallet := walletFromKey(generateKey(t, false), "")
+
+	if err := ws.Import(wallet); err != nil {
+		t.Fatal(err)
+	}
+
+	err := ws.Import(walletFromKey(wallet.PrivateKey, ""))
+	if !errors.Is(err, ErrWalletExists) || !strings.Contains(err.Error(), string(wallet.Address())) {
+		t.Fatalf("Import() error = %v, want %v", err, ErrWalletExists)
+	}
+}
@@ -1,23 +1,33 @@
 package wallets
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
 
 	"github.com/mr-tron/base58"
 	"golang.org/x/crypto/ripemd160"
 )
 
 const (
-	ChecksumLength = 4
-	Version        = byte(0x00)
+	ChecksumLength   = 4
+	PubKeyHashLength = 20
+	Version          = byte(0x00)
 )
 
+var ErrInvalidAddress = errors.New("invalid address")
+
 type Wallet struct {
 	PrivateKey *ecdsa.PrivateKey
 	PublicKey  []byte
+	// Path is the derivation path of the keys derived from the wallet seed,
+	// empty for random keys
+	Path string
 }
 
 func NewWallet() (*Wallet, error) {
@@ -28,27 +38,55 @@ func NewWallet() (*Wallet, error) {
 		return nil, err
 	}
 
-	pub := append(private.PublicKey.X.Bytes(), private.PublicKey.Y.Bytes()...)
+	return walletFromKey(private, ""), nil
+}
 
+func walletFromKey(private *ecdsa.PrivateKey, path string) *Wallet {
 	return &Wallet{
 		PrivateKey: private,
-		PublicKey:  pub,
-	}, nil
+		PublicKey:  PublicKeyBytes(&private.PublicKey),
+		Path:       path,
+	}
 }
 
-func (w *Wallet) Address() []byte {
-	pubHash := w.PublicKeyHash()
+// newPrivateKey returns the P-256 key of the big endian scalar d, which must
+// be checked with validKey.
+func newPrivateKey(d []byte) *ecdsa.PrivateKey {
+	private := &ecdsa.PrivateKey{D: new(big.Int).SetBytes(d)}
+	private.PublicKey.Curve = elliptic.P256()
+	private.PublicKey.X, private.PublicKey.Y = elliptic.P256().ScalarBaseMult(d)
 
-	versionedHash := append([]byte{Version}, pubHash...)
-	checksum := Checksum(versionedHash)
+	return private
+}
 
-	fullHash := append(versionedHash, checksum...)
+func validKey(d []byte) bool {
+	k := new(big.Int).SetBytes(d)
 
-	return []byte(base58.Encode(fullHash))
+	return k.Sign() != 0 && k.Cmp(elliptic.P256().Params().N) < 0
+}
+
+func (w *Wallet) Address() []byte {
+	return EncodeAddress(w.PublicKeyHash())
 }
 
 func (w *Wallet) PublicKeyHash() []byte {
-	pubHash := sha256.Sum256(w.PublicKey)
+	return HashPubKey(w.PublicKey)
+}
+
+// PublicKeyBytes encodes the public key as the fixed width X and Y
+// coordinates concatenated.
+func PublicKeyBytes(pub *ecdsa.PublicKey) []byte {
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	buff := make([]byte, 2*size)
+
+	pub.X.FillBytes(buff[:size])
+	pub.Y.FillBytes(buff[size:])
+
+	return buff
+}
+
+func HashPubKey(pubKey []byte) []byte {
+	pubHash := sha256.Sum256(pubKey)
 
 	hasher := ripemd160.New()
 	_, err := hasher.Write(pubHash[:])
@@ -59,6 +97,48 @@ func (w *Wallet) PublicKeyHash() []byte {
 	return hasher.Sum(nil)
 }
 
+// EncodeAddress builds the base58 address of a public key hash.
+func EncodeAddress(pubKeyHash []byte) []byte {
+	versionedHash := append([]byte{Version}, pubKeyHash...)
+	checksum := Checksum(versionedHash)
+
+	fullHash := append(versionedHash, checksum...)
+
+	return []byte(base58.Encode(fullHash))
+}
+
+// DecodeAddress validates the version and checksum of a base58 address and
+// returns the public key hash it encodes.
+func DecodeAddress(address []byte) ([]byte, error) {
+	fullHash, err := base58.Decode(string(address))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAddress, err)
+	}
+
+	if len(fullHash) != 1+PubKeyHashLength+ChecksumLength {
+		return nil, fmt.Errorf("%w: unexpected length %d", ErrInvalidAddress, len(fullHash))
+	}
+
+	versionedHash := fullHash[:len(fullHash)-ChecksumLength]
+	checksum := fullHash[len(fullHash)-ChecksumLength:]
+
+	if versionedHash[0] != Version {
+		return nil, fmt.Errorf("%w: unknown version %d", ErrInvalidAddress, versionedHash[0])
+	}
+
+	if !bytes.Equal(Checksum(versionedHash), checksum) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidAddress)
+	}
+
+	return versionedHash[1:], nil
+}
+
+func ValidateAddress(address []byte) bool {
+	_, err := DecodeAddress(address)
+
+	return err == nil
+}
+
 func Checksum(payload []byte) []byte {
 	firstHash := sha256.Sum256(payload)
 	secondHash := sha256.Sum256(firstHash[:])
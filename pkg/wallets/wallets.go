@@ -1,26 +1,51 @@
 package wallets
 
 import (
-	"bytes"
-	"crypto/elliptic"
-	"encoding/gob"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
 )
 
+// GapLimit is the number of consecutive unused addresses after which a
+// restore stops looking for used ones.
+const GapLimit = 20
+
+var ErrFileExists = errors.New("wallet file already exists")
+
 type Wallets struct {
 	FilePath string
 	Items    map[string]*Wallet
-}
+	// Seed derives the addresses of deterministic wallets, it is nil when
+	// the addresses have random keys
+	Seed []byte
+	// NextIndex is the index of the next derived address
+	NextIndex uint32
+	// Renamed maps the old addresses of the keys whose address changed
+	// when Load migrated a gob file to their current address. The outputs
+	// sent to an old address cannot be spent anymore.
+	Renamed map[string]string
 
-func init() {
-	gob.Register(map[string]*Wallet{})
-	gob.Register(elliptic.P256())
+	// passphrase encrypts the file on Save, the file is kept in clear when
+	// it is empty
+	passphrase []byte
 }
 
-func New(filePath string) (*Wallets, error) {
+// PassphraseFunc returns the passphrase of an encrypted wallet file. It is
+// only called when the file is encrypted.
+type PassphraseFunc func() ([]byte, error)
+
+// New creates an empty wallet file, encrypted with the passphrase unless it
+// is empty. It fails with ErrFileExists instead of replacing a wallet file.
+func New(filePath string, passphrase []byte) (*Wallets, error) {
+	if err := CheckNotExists(filePath); err != nil {
+		return nil, err
+	}
+
 	ws := &Wallets{
-		FilePath: filePath,
-		Items:    make(map[string]*Wallet, 0),
+		FilePath:   filePath,
+		Items:      make(map[string]*Wallet, 0),
+		passphrase: passphrase,
 	}
 
 	if err := ws.Save(); err != nil {
@@ -30,16 +55,126 @@ func New(filePath string) (*Wallets, error) {
 	return ws, nil
 }
 
-func (ws *Wallets) Save() error {
-	var content bytes.Buffer
+// NewHD creates a deterministic wallet file whose addresses are derived
+// from the mnemonic, encrypted with the passphrase unless it is empty. It
+// fails with ErrFileExists instead of replacing a wallet file.
+func NewHD(filePath string, mnemonic string, passphrase []byte) (*Wallets, error) {
+	ws, err := newHD(filePath, mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
 
-	encoder := gob.NewEncoder(&content)
-	err := encoder.Encode(ws)
+	if err := ws.Save(); err != nil {
+		return nil, err
+	}
+
+	return ws, nil
+}
+
+// Restore creates a deterministic wallet file from the mnemonic and derives
+// its addresses until GapLimit consecutive ones are unused, keeping every
+// address up to the last used one. used reports whether a public key hash
+// received an output. The first address is kept when none is used.
+func Restore(filePath string, mnemonic string, passphrase []byte, used func(pubKeyHash []byte) bool) (*Wallets, error) {
+	ws, err := newHD(filePath, mnemonic, passphrase)
 	if err != nil {
+		return nil, err
+	}
+
+	var derived []*Wallet
+	lastUsed := 0
+
+	for index, gap := uint32(0), 0; gap < GapLimit; index++ {
+		wallet, err := DeriveWallet(ws.Seed, AddressPath(index))
+		if err != nil {
+			return nil, err
+		}
+
+		derived = append(derived, wallet)
+
+		if used(wallet.PublicKeyHash()) {
+			lastUsed = int(index)
+			gap = 0
+		} else {
+			gap++
+		}
+	}
+
+	for _, wallet := range derived[:lastUsed+1] {
+		ws.Items[string(wallet.Address())] = wallet
+	}
+	ws.NextIndex = uint32(lastUsed + 1)
+
+	if err := ws.Save(); err != nil {
+		return nil, err
+	}
+
+	return ws, nil
+}
+
+func newHD(filePath string, mnemonic string, passphrase []byte) (*Wallets, error) {
+	if err := CheckNotExists(filePath); err != nil {
+		return nil, err
+	}
+
+	mnemonic, err := NormalizeMnemonic(mnemonic)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Wallets{
+		FilePath:   filePath,
+		Items:      make(map[string]*Wallet, 0),
+		Seed:       MnemonicSeed(mnemonic, ""),
+		passphrase: passphrase,
+	}, nil
+}
+
+// CheckNotExists fails with ErrFileExists when there is already a file at
+// filePath, so creating a wallet never replaces another one.
+func CheckNotExists(filePath string) error {
+	if _, err := os.Stat(filePath); err == nil {
+		return fmt.Errorf("%w: %s", ErrFileExists, filePath)
+	} else if !os.IsNotExist(err) {
 		return err
 	}
 
-	return os.WriteFile(ws.FilePath, content.Bytes(), 0644)
+	return nil
+}
+
+// Save writes the wallets, encrypted when a passphrase is set. The file is
+// only readable by its owner and replaced atomically, so a failed write
+// never leaves a truncated wallet behind.
+func (ws *Wallets) Save() error {
+	data, err := ws.marshal()
+	if err != nil {
+		return err
+	}
+
+	if ws.Encrypted() {
+		data, err = encrypt(data, ws.passphrase)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeFile(ws.FilePath, data)
+}
+
+// Deterministic reports whether the addresses are derived from a seed.
+func (ws *Wallets) Deterministic() bool {
+	return len(ws.Seed) > 0
+}
+
+// Encrypted reports whether Save encrypts the file.
+func (ws *Wallets) Encrypted() bool {
+	return len(ws.passphrase) > 0
+}
+
+// SetPassphrase changes the passphrase used by the next Save. An empty
+// passphrase saves the file in clear.
+func (ws *Wallets) SetPassphrase(passphrase []byte) {
+	ws.passphrase = passphrase
 }
 
 func (ws *Wallets) Wallet(address string) *Wallet {
@@ -50,33 +185,135 @@ func (ws *Wallets) Wallet(address string) *Wallet {
 	return nil
 }
 
+// NewWallet adds an address, derived at the next index for deterministic
+// wallets and random otherwise.
 func (ws *Wallets) NewWallet() (*Wallet, error) {
-	wallet, err := NewWallet()
+	var wallet *Wallet
+	var err error
+
+	if ws.Deterministic() {
+		wallet, err = DeriveWallet(ws.Seed, AddressPath(ws.NextIndex))
+	} else {
+		wallet, err = NewWallet()
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	if ws.Deterministic() {
+		ws.NextIndex++
+	}
+
 	ws.Items[string(wallet.Address())] = wallet
 
 	return wallet, nil
 }
 
-func Load(filePath string) (*Wallets, error) {
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+// Load reads a wallet file. The passphrase function is called for encrypted
+// files, which fail with ErrLocked when it is nil, ErrWrongPassphrase when
+// the passphrase does not match and ErrTampered when the file was changed.
+//
+// Gob files of the earlier releases are rewritten in the current format,
+// keeping the encryption, with a copy of the original file left next to it.
+// The keys whose address changed on the way are listed in Renamed.
+func Load(filePath string, passphrase PassphraseFunc) (*Wallets, error) {
+	original, err := os.ReadFile(filePath)
+	if err != nil {
 		return nil, err
 	}
 
-	var wallets Wallets
+	data := original
+
+	var key []byte
+	if env, ok := parseEnvelope(data); ok {
+		if passphrase == nil {
+			return nil, fmt.Errorf("%s: %w", filePath, ErrLocked)
+		}
+
+		key, err = passphrase()
+		if err != nil {
+			return nil, err
+		}
+
+		data, err = decrypt(env, key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filePath, err)
+		}
+	}
+
+	if isDocument(data) {
+		ws, err := unmarshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filePath, err)
+		}
+
+		ws.FilePath = filePath
+		ws.passphrase = key
+
+		return ws, nil
+	}
+
+	ws, err := unmarshalLegacy(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", filePath, ErrTampered, err)
+	}
+
+	ws.FilePath = filePath
+	ws.passphrase = key
+
+	if err := writeFile(filePath+legacyBackupSuffix, original); err != nil {
+		return nil, fmt.Errorf("%s: backup before migration: %w", filePath, err)
+	}
+
+	if err := ws.Save(); err != nil {
+		return nil, fmt.Errorf("%s: migration: %w", filePath, err)
+	}
+
+	return ws, nil
+}
+
+// IsEncrypted reports whether the wallet file is encrypted, without needing
+// its passphrase.
+func IsEncrypted(filePath string) (bool, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
-		return nil, err
+		return false, err
 	}
 
-	decoder := gob.NewDecoder(bytes.NewReader(data))
-	err = decoder.Decode(&wallets)
+	_, ok := parseEnvelope(data)
+
+	return ok, nil
+}
+
+// writeFile replaces the file at path with data through a temporary file in
+// the same directory.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	// Removing fails harmlessly once the file was renamed
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
 	}
 
-	return &wallets, nil
+	return os.Rename(tmp.Name(), path)
 }
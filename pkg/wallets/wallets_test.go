@@ -0,0 +1,188 @@
+package wallets
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateRefusesExistingFile(t *testing.T) {
+	mnemonic := mnemonicVectors[0].mnemonic
+
+	tests := []struct {
+		name   string
+		create func(path string) error
+	}{
+		{"New", func(path string) error {
+			_, err := New(path, nil)
+			return err
+		}},
+		{"NewHD", func(path string) error {
+			_, err := NewHD(path, mnemonic, nil)
+			return err
+		}},
+		{"Restore", func(path string) error {
+			_, err := Restore(path, mnemonic, nil, func([]byte) bool { return false })
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "wallet.json")
+
+			if err := tt.create(path); err != nil {
+				t.Fatal(err)
+			}
+
+			before, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.create(path); !errors.Is(err, ErrFileExists) {
+				t.Fatalf("error = %v, want %v", err, ErrFileExists)
+			}
+
+			after, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(before) != string(after) {
+				t.Fatal("existing wallet file was replaced")
+			}
+		})
+	}
+}
+
+func TestLoadEncrypted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wallet.json")
+	passphrase := []byte("correct horse")
+
+	ws, err := New(path, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wallet, err := ws.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ws.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted, err := IsEncrypted(path)
+	if err != nil || !encrypted {
+		t.Fatalf("IsEncrypted() = %v, %v, want true", encrypted, err)
+	}
+
+	loaded, err := Load(path, func() ([]byte, error) { return passphrase, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Wallet(string(wallet.Address())) == nil || !loaded.Encrypted() {
+		t.Fatal("loaded wallet file lost its key or its encryption")
+	}
+
+	if _, err := Load(path, nil); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Load() without passphrase error = %v, want %v", err, ErrLocked)
+	}
+
+	if _, err := Load(path, func() ([]byte, error) { return []byte("wrong"), nil }); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("Load() with a wrong passphrase error = %v, want %v", err, ErrWrongPassphrase)
+	}
+
+	// Flip a ciphertext byte
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env, ok := parseEnvelope(data)
+	if !ok {
+		t.Fatal("encrypted file is not an envelope")
+	}
+
+	ciphertext, err := hex.DecodeString(env.Ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ciphertext[len(ciphertext)/2] ^= 1
+	env.Ciphertext = hex.EncodeToString(ciphertext)
+
+	data, err = json.Marshal(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(path, func() ([]byte, error) { return passphrase, nil }); !errors.Is(err, ErrTampered) {
+		t.Fatalf("Load() of a modified file error = %v, want %v", err, ErrTampered)
+	}
+}
+
+func TestSaveReplacesFileAtomically(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "wallet.json")
+
+	ws, err := New(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted, err := IsEncrypted(path)
+	if err != nil || encrypted {
+		t.Fatalf("IsEncrypted() = %v, %v, want false", encrypted, err)
+	}
+
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ws.NewWallet(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ws.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mode := after.Mode().Perm(); mode != 0600 {
+		t.Fatalf("file mode = %o, want 600", mode)
+	}
+
+	// The file is renamed over the old one instead of being rewritten
+	if os.SameFile(before, after) {
+		t.Fatal("wallet file rewritten in place")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("%d files left in the wallet directory, want 1", len(entries))
+	}
+}
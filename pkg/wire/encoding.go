@@ -0,0 +1,229 @@
+package wire
+
+import (
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func MarshalBlock(blk *block.Block) []byte {
+	var b []byte
+
+	b = appendVarint(b, 1, uint64(blk.Version))
+	b = appendVarint(b, 2, blk.Height)
+	b = appendInt(b, 3, blk.Timestamp)
+	b = appendVarint(b, 4, uint64(blk.Bits))
+	b = appendBytes(b, 5, blk.PrevHash)
+	b = appendBytes(b, 6, blk.MerkleRoot)
+	b = appendVarint(b, 7, blk.Nonce)
+	b = appendBytes(b, 8, blk.Hash)
+
+	for _, tx := range blk.Transactions {
+		b = appendMessage(b, 9, MarshalTransaction(tx))
+	}
+
+	return b
+}
+
+func UnmarshalBlock(data []byte) (*block.Block, error) {
+	var blk block.Block
+
+	err := consumeFields(data, func(num protowire.Number, typ protowire.Type, data []byte) (int, error) {
+		switch num {
+		case 1:
+			v, n, err := consumeVarint(typ, data)
+			blk.Version = int32(v)
+
+			return n, err
+		case 2:
+			v, n, err := consumeVarint(typ, data)
+			blk.Height = v
+
+			return n, err
+		case 3:
+			v, n, err := consumeInt(typ, data)
+			blk.Timestamp = v
+
+			return n, err
+		case 4:
+			v, n, err := consumeVarint(typ, data)
+			blk.Bits = uint32(v)
+
+			return n, err
+		case 5:
+			v, n, err := consumeBytes(typ, data)
+			blk.PrevHash = v
+
+			return n, err
+		case 6:
+			v, n, err := consumeBytes(typ, data)
+			blk.MerkleRoot = v
+
+			return n, err
+		case 7:
+			v, n, err := consumeVarint(typ, data)
+			blk.Nonce = v
+
+			return n, err
+		case 8:
+			v, n, err := consumeBytes(typ, data)
+			blk.Hash = v
+
+			return n, err
+		case 9:
+			v, n, err := consumeBytes(typ, data)
+			if err != nil {
+				return 0, err
+			}
+
+			tx, err := UnmarshalTransaction(v)
+			if err != nil {
+				return 0, err
+			}
+
+			blk.Transactions = append(blk.Transactions, tx)
+
+			return n, nil
+		}
+
+		return 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &blk, nil
+}
+
+func MarshalTransaction(tx *transaction.Transaction) []byte {
+	var b []byte
+
+	b = appendBytes(b, 1, tx.ID)
+
+	for _, in := range tx.Inputs {
+		var inBytes []byte
+
+		inBytes = appendBytes(inBytes, 1, in.ID)
+		inBytes = appendInt(inBytes, 2, int64(in.Out))
+		inBytes = appendBytes(inBytes, 3, in.Signature)
+		inBytes = appendBytes(inBytes, 4, in.PubKey)
+
+		b = appendMessage(b, 2, inBytes)
+	}
+
+	for _, out := range tx.Outputs {
+		var outBytes []byte
+
+		outBytes = appendInt(outBytes, 1, int64(out.Value))
+		outBytes = appendBytes(outBytes, 2, out.PubKeyHash)
+
+		b = appendMessage(b, 3, outBytes)
+	}
+
+	return b
+}
+
+func UnmarshalTransaction(data []byte) (*transaction.Transaction, error) {
+	var tx transaction.Transaction
+
+	err := consumeFields(data, func(num protowire.Number, typ protowire.Type, data []byte) (int, error) {
+		switch num {
+		case 1:
+			v, n, err := consumeBytes(typ, data)
+			tx.ID = v
+
+			return n, err
+		case 2:
+			v, n, err := consumeBytes(typ, data)
+			if err != nil {
+				return 0, err
+			}
+
+			in, err := unmarshalInput(v)
+			if err != nil {
+				return 0, err
+			}
+
+			tx.Inputs = append(tx.Inputs, in)
+
+			return n, nil
+		case 3:
+			v, n, err := consumeBytes(typ, data)
+			if err != nil {
+				return 0, err
+			}
+
+			out, err := unmarshalOutput(v)
+			if err != nil {
+				return 0, err
+			}
+
+			tx.Outputs = append(tx.Outputs, out)
+
+			return n, nil
+		}
+
+		return 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &tx, nil
+}
+
+func unmarshalInput(data []byte) (transaction.Input, error) {
+	var in transaction.Input
+
+	err := consumeFields(data, func(num protowire.Number, typ protowire.Type, data []byte) (int, error) {
+		switch num {
+		case 1:
+			v, n, err := consumeBytes(typ, data)
+			in.ID = v
+
+			return n, err
+		case 2:
+			v, n, err := consumeInt(typ, data)
+			in.Out = int(v)
+
+			return n, err
+		case 3:
+			v, n, err := consumeBytes(typ, data)
+			in.Signature = v
+
+			return n, err
+		case 4:
+			v, n, err := consumeBytes(typ, data)
+			in.PubKey = v
+
+			return n, err
+		}
+
+		return 0, nil
+	})
+
+	return in, err
+}
+
+func unmarshalOutput(data []byte) (transaction.Output, error) {
+	var out transaction.Output
+
+	err := consumeFields(data, func(num protowire.Number, typ protowire.Type, data []byte) (int, error) {
+		switch num {
+		case 1:
+			v, n, err := consumeInt(typ, data)
+			out.Value = int(v)
+
+			return n, err
+		case 2:
+			v, n, err := consumeBytes(typ, data)
+			out.PubKeyHash = v
+
+			return n, err
+		}
+
+		return 0, nil
+	})
+
+	return out, err
+}
@@ -0,0 +1,213 @@
+package wire
+
+import (
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// AnnounceBlock tells peers a new block is available under the given CID.
+type AnnounceBlock struct {
+	Hash   []byte
+	Height uint64
+	CID    string
+}
+
+func (m *AnnounceBlock) Type() MessageType { return TypeAnnounceBlock }
+
+func (m *AnnounceBlock) Marshal() []byte {
+	var b []byte
+
+	b = appendBytes(b, 1, m.Hash)
+	b = appendVarint(b, 2, m.Height)
+	b = appendString(b, 3, m.CID)
+
+	return b
+}
+
+func (m *AnnounceBlock) Unmarshal(data []byte) error {
+	*m = AnnounceBlock{}
+
+	return consumeFields(data, func(num protowire.Number, typ protowire.Type, data []byte) (int, error) {
+		switch num {
+		case 1:
+			v, n, err := consumeBytes(typ, data)
+			m.Hash = v
+
+			return n, err
+		case 2:
+			v, n, err := consumeVarint(typ, data)
+			m.Height = v
+
+			return n, err
+		case 3:
+			v, n, err := consumeBytes(typ, data)
+			m.CID = string(v)
+
+			return n, err
+		}
+
+		return 0, nil
+	})
+}
+
+// AnnounceTx carries a new transaction.
+type AnnounceTx struct {
+	Tx *transaction.Transaction
+}
+
+func (m *AnnounceTx) Type() MessageType { return TypeAnnounceTx }
+
+func (m *AnnounceTx) Marshal() []byte {
+	var b []byte
+
+	if m.Tx != nil {
+		b = appendMessage(b, 1, MarshalTransaction(m.Tx))
+	}
+
+	return b
+}
+
+func (m *AnnounceTx) Unmarshal(data []byte) error {
+	*m = AnnounceTx{}
+
+	err := consumeFields(data, func(num protowire.Number, typ protowire.Type, data []byte) (int, error) {
+		if num != 1 {
+			return 0, nil
+		}
+
+		v, n, err := consumeBytes(typ, data)
+		if err != nil {
+			return 0, err
+		}
+
+		m.Tx, err = UnmarshalTransaction(v)
+
+		return n, err
+	})
+	if err != nil {
+		return err
+	}
+
+	if m.Tx == nil {
+		return ErrMalformed
+	}
+
+	return nil
+}
+
+// RequestBlock asks peers for the block with the given hash.
+type RequestBlock struct {
+	Hash []byte
+}
+
+func (m *RequestBlock) Type() MessageType { return TypeRequestBlock }
+
+func (m *RequestBlock) Marshal() []byte {
+	return appendBytes(nil, 1, m.Hash)
+}
+
+func (m *RequestBlock) Unmarshal(data []byte) error {
+	*m = RequestBlock{}
+
+	return consumeFields(data, func(num protowire.Number, typ protowire.Type, data []byte) (int, error) {
+		if num != 1 {
+			return 0, nil
+		}
+
+		v, n, err := consumeBytes(typ, data)
+		m.Hash = v
+
+		return n, err
+	})
+}
+
+// Response answers a RequestBlock. Block is nil when the peer does not have
+// the requested block.
+type Response struct {
+	Hash  []byte
+	Block *block.Block
+}
+
+func (m *Response) Type() MessageType { return TypeResponse }
+
+func (m *Response) Marshal() []byte {
+	var b []byte
+
+	b = appendBytes(b, 1, m.Hash)
+	if m.Block != nil {
+		b = appendMessage(b, 2, MarshalBlock(m.Block))
+	}
+
+	return b
+}
+
+func (m *Response) Unmarshal(data []byte) error {
+	*m = Response{}
+
+	return consumeFields(data, func(num protowire.Number, typ protowire.Type, data []byte) (int, error) {
+		switch num {
+		case 1:
+			v, n, err := consumeBytes(typ, data)
+			m.Hash = v
+
+			return n, err
+		case 2:
+			v, n, err := consumeBytes(typ, data)
+			if err != nil {
+				return 0, err
+			}
+
+			m.Block, err = UnmarshalBlock(v)
+
+			return n, err
+		}
+
+		return 0, nil
+	})
+}
+
+// Status advertises the chain tip of a peer.
+type Status struct {
+	Height  uint64
+	TipHash []byte
+	TipCID  string
+}
+
+func (m *Status) Type() MessageType { return TypeStatus }
+
+func (m *Status) Marshal() []byte {
+	var b []byte
+
+	b = appendVarint(b, 1, m.Height)
+	b = appendBytes(b, 2, m.TipHash)
+	b = appendString(b, 3, m.TipCID)
+
+	return b
+}
+
+func (m *Status) Unmarshal(data []byte) error {
+	*m = Status{}
+
+	return consumeFields(data, func(num protowire.Number, typ protowire.Type, data []byte) (int, error) {
+		switch num {
+		case 1:
+			v, n, err := consumeVarint(typ, data)
+			m.Height = v
+
+			return n, err
+		case 2:
+			v, n, err := consumeBytes(typ, data)
+			m.TipHash = v
+
+			return n, err
+		case 3:
+			v, n, err := consumeBytes(typ, data)
+			m.TipCID = string(v)
+
+			return n, err
+		}
+
+		return 0, nil
+	})
+}
@@ -0,0 +1,286 @@
+// Package wire implements the messages exchanged between peers over pubsub.
+//
+// Every message is an Envelope carrying the protocol version, the network ID
+// and a typed payload. Envelopes and payloads are encoded with the protobuf
+// wire format: fields are written in increasing field number order and zero
+// values are omitted, so the same message always produces the same bytes.
+// Unknown fields are skipped when decoding so newer peers can add fields
+// without breaking older ones.
+package wire
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	// ProtocolVersion is the version of the messages produced by this node
+	ProtocolVersion uint32 = 1
+	// MinProtocolVersion is the oldest version this node understands
+	MinProtocolVersion uint32 = 1
+)
+
+type MessageType uint32
+
+const (
+	TypeAnnounceBlock MessageType = iota + 1
+	TypeAnnounceTx
+	TypeRequestBlock
+	TypeResponse
+	TypeStatus
+)
+
+func (t MessageType) String() string {
+	switch t {
+	case TypeAnnounceBlock:
+		return "announce-block"
+	case TypeAnnounceTx:
+		return "announce-tx"
+	case TypeRequestBlock:
+		return "request-block"
+	case TypeResponse:
+		return "response"
+	case TypeStatus:
+		return "status"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(t))
+}
+
+var (
+	ErrMalformed           = errors.New("malformed message")
+	ErrWrongNetwork        = errors.New("message from a different network")
+	ErrIncompatibleVersion = errors.New("incompatible protocol version")
+	ErrUnknownType         = errors.New("unknown message type")
+)
+
+// Envelope wraps every message sent between peers.
+type Envelope struct {
+	Version   uint32
+	NetworkID string
+	Type      MessageType
+	Payload   []byte
+}
+
+// Message is implemented by every payload that can be put in an Envelope.
+type Message interface {
+	Type() MessageType
+	Marshal() []byte
+	Unmarshal(data []byte) error
+}
+
+// Wrap builds the envelope of msg for the given network.
+func Wrap(networkID string, msg Message) *Envelope {
+	return &Envelope{
+		Version:   ProtocolVersion,
+		NetworkID: networkID,
+		Type:      msg.Type(),
+		Payload:   msg.Marshal(),
+	}
+}
+
+// Encode wraps msg in an envelope for the given network and encodes it.
+func Encode(networkID string, msg Message) []byte {
+	return Wrap(networkID, msg).Marshal()
+}
+
+// Decode decodes an envelope, checks it belongs to the given network and
+// speaks a compatible version, and decodes its payload.
+func Decode(networkID string, data []byte) (*Envelope, Message, error) {
+	var env Envelope
+	if err := env.Unmarshal(data); err != nil {
+		return nil, nil, err
+	}
+
+	if err := env.Check(networkID); err != nil {
+		return &env, nil, err
+	}
+
+	msg, err := env.Open()
+	if err != nil {
+		return &env, nil, err
+	}
+
+	return &env, msg, nil
+}
+
+// Check validates the envelope network and version.
+func (e *Envelope) Check(networkID string) error {
+	if e.NetworkID != networkID {
+		return fmt.Errorf("%w: got %q, expected %q", ErrWrongNetwork, e.NetworkID, networkID)
+	}
+
+	if e.Version < MinProtocolVersion || e.Version > ProtocolVersion {
+		return fmt.Errorf("%w: got %d, supported %d to %d", ErrIncompatibleVersion, e.Version, MinProtocolVersion, ProtocolVersion)
+	}
+
+	return nil
+}
+
+// Open decodes the envelope payload according to its type.
+func (e *Envelope) Open() (Message, error) {
+	var msg Message
+
+	switch e.Type {
+	case TypeAnnounceBlock:
+		msg = &AnnounceBlock{}
+	case TypeAnnounceTx:
+		msg = &AnnounceTx{}
+	case TypeRequestBlock:
+		msg = &RequestBlock{}
+	case TypeResponse:
+		msg = &Response{}
+	case TypeStatus:
+		msg = &Status{}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownType, e.Type)
+	}
+
+	if err := msg.Unmarshal(e.Payload); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+func (e *Envelope) Marshal() []byte {
+	var b []byte
+
+	b = appendVarint(b, 1, uint64(e.Version))
+	b = appendString(b, 2, e.NetworkID)
+	b = appendVarint(b, 3, uint64(e.Type))
+	b = appendBytes(b, 4, e.Payload)
+
+	return b
+}
+
+func (e *Envelope) Unmarshal(data []byte) error {
+	*e = Envelope{}
+
+	return consumeFields(data, func(num protowire.Number, typ protowire.Type, data []byte) (int, error) {
+		switch num {
+		case 1:
+			v, n, err := consumeVarint(typ, data)
+			e.Version = uint32(v)
+
+			return n, err
+		case 2:
+			v, n, err := consumeBytes(typ, data)
+			e.NetworkID = string(v)
+
+			return n, err
+		case 3:
+			v, n, err := consumeVarint(typ, data)
+			e.Type = MessageType(v)
+
+			return n, err
+		case 4:
+			v, n, err := consumeBytes(typ, data)
+			e.Payload = v
+
+			return n, err
+		}
+
+		return 0, nil
+	})
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+
+	return protowire.AppendVarint(b, v)
+}
+
+func appendInt(b []byte, num protowire.Number, v int64) []byte {
+	return appendVarint(b, num, protowire.EncodeZigZag(v))
+}
+
+func appendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+
+	return protowire.AppendBytes(b, v)
+}
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	return appendBytes(b, num, []byte(v))
+}
+
+// appendMessage appends an embedded message even when empty, so repeated
+// fields keep every element.
+func appendMessage(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+
+	return protowire.AppendBytes(b, v)
+}
+
+// consumeFields calls fn for each field in data. fn returns how many bytes
+// of the field value it consumed, zero meaning the field is unknown and must
+// be skipped.
+func consumeFields(data []byte, fn func(num protowire.Number, typ protowire.Type, data []byte) (int, error)) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return fmt.Errorf("%w: %s", ErrMalformed, protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		n, err := fn(num, typ, data)
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return fmt.Errorf("%w: %s", ErrMalformed, protowire.ParseError(n))
+			}
+		}
+
+		data = data[n:]
+	}
+
+	return nil
+}
+
+func consumeVarint(typ protowire.Type, data []byte) (uint64, int, error) {
+	if typ != protowire.VarintType {
+		return 0, 0, fmt.Errorf("%w: expected varint, got wire type %d", ErrMalformed, typ)
+	}
+
+	v, n := protowire.ConsumeVarint(data)
+	if n < 0 {
+		return 0, 0, fmt.Errorf("%w: %s", ErrMalformed, protowire.ParseError(n))
+	}
+
+	return v, n, nil
+}
+
+func consumeInt(typ protowire.Type, data []byte) (int64, int, error) {
+	v, n, err := consumeVarint(typ, data)
+
+	return protowire.DecodeZigZag(v), n, err
+}
+
+func consumeBytes(typ protowire.Type, data []byte) ([]byte, int, error) {
+	if typ != protowire.BytesType {
+		return nil, 0, fmt.Errorf("%w: expected bytes, got wire type %d", ErrMalformed, typ)
+	}
+
+	v, n := protowire.ConsumeBytes(data)
+	if n < 0 {
+		return nil, 0, fmt.Errorf("%w: %s", ErrMalformed, protowire.ParseError(n))
+	}
+
+	// Copy so decoded messages do not keep the whole buffer alive
+	return append([]byte(nil), v...), n, nil
+}
@@ -0,0 +1,242 @@
+package wire
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/herlon214/ipfs-blockchain/pkg/block"
+	"github.com/herlon214/ipfs-blockchain/pkg/transaction"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const testNetwork = "test"
+
+func testTransaction() *transaction.Transaction {
+	return &transaction.Transaction{
+		ID: []byte("transaction id"),
+		Inputs: []transaction.Input{
+			{ID: []byte("previous"), Out: 3, Signature: []byte("signature"), PubKey: []byte("public key")},
+			{Out: -1, PubKey: []byte("coinbase data")},
+		},
+		Outputs: []transaction.Output{
+			{Value: 100, PubKeyHash: []byte("hash")},
+			// Values are checked by the chain, the encoding keeps them as is
+			{Value: -5, PubKeyHash: []byte("other hash")},
+		},
+	}
+}
+
+func testBlock() *block.Block {
+	return &block.Block{
+		Header: block.Header{
+			Version:    block.Version,
+			Height:     42,
+			Timestamp:  1600000000,
+			Bits:       15,
+			PrevHash:   []byte("previous block"),
+			MerkleRoot: []byte("merkle root"),
+			Nonce:      1 << 40,
+		},
+		Hash:         []byte("block hash"),
+		Transactions: []*transaction.Transaction{testTransaction(), testTransaction()},
+	}
+}
+
+func testMessages() []Message {
+	return []Message{
+		&AnnounceBlock{Hash: []byte("hash"), Height: 7, CID: "bafy"},
+		&AnnounceBlock{},
+		&AnnounceTx{Tx: testTransaction()},
+		&RequestBlock{Hash: []byte("hash")},
+		&RequestBlock{},
+		&Response{Hash: []byte("hash"), Block: testBlock()},
+		&Response{Hash: []byte("missing")},
+		&Status{Height: 1 << 62, TipHash: []byte("tip"), TipCID: "bafy"},
+		&Status{},
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	for _, msg := range testMessages() {
+		t.Run(msg.Type().String(), func(t *testing.T) {
+			data := Encode(testNetwork, msg)
+
+			env, decoded, err := Decode(testNetwork, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if env.Version != ProtocolVersion || env.NetworkID != testNetwork || env.Type != msg.Type() {
+				t.Fatalf("envelope = %+v", env)
+			}
+
+			if !reflect.DeepEqual(decoded, msg) {
+				t.Fatalf("decoded %+v, want %+v", decoded, msg)
+			}
+
+			if !bytes.Equal(Encode(testNetwork, decoded), data) {
+				t.Fatal("encoding the decoded message gives different bytes")
+			}
+		})
+	}
+}
+
+func TestDecodeSkipsUnknownFields(t *testing.T) {
+	msg := &Status{Height: 3, TipHash: []byte("tip"), TipCID: "bafy"}
+
+	payload := msg.Marshal()
+	payload = protowire.AppendTag(payload, 100, protowire.BytesType)
+	payload = protowire.AppendBytes(payload, []byte("added by a newer peer"))
+	payload = protowire.AppendTag(payload, 101, protowire.VarintType)
+	payload = protowire.AppendVarint(payload, 7)
+
+	env := Wrap(testNetwork, msg)
+	env.Payload = payload
+
+	_, decoded, err := Decode(testNetwork, env.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(decoded, msg) {
+		t.Fatalf("decoded %+v, want %+v", decoded, msg)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	valid := Encode(testNetwork, &AnnounceBlock{Hash: []byte("hash"), Height: 7, CID: "bafy"})
+
+	withEnvelope := func(fn func(env *Envelope)) []byte {
+		env := Wrap(testNetwork, &Response{Hash: []byte("hash"), Block: testBlock()})
+		fn(env)
+
+		return env.Marshal()
+	}
+
+	tests := []struct {
+		name  string
+		data  []byte
+		match error
+	}{
+		{
+			name:  "wrong network",
+			data:  Encode("main", &Status{Height: 1}),
+			match: ErrWrongNetwork,
+		},
+		{
+			name:  "version too old",
+			data:  withEnvelope(func(env *Envelope) { env.Version = MinProtocolVersion - 1 }),
+			match: ErrIncompatibleVersion,
+		},
+		{
+			name:  "version too new",
+			data:  withEnvelope(func(env *Envelope) { env.Version = ProtocolVersion + 1 }),
+			match: ErrIncompatibleVersion,
+		},
+		{
+			name:  "unknown type",
+			data:  withEnvelope(func(env *Envelope) { env.Type = TypeStatus + 1 }),
+			match: ErrUnknownType,
+		},
+		{
+			name:  "truncated envelope",
+			data:  valid[:len(valid)-1],
+			match: ErrMalformed,
+		},
+		{
+			name:  "truncated tag",
+			data:  append(append([]byte{}, valid...), 0x80),
+			match: ErrMalformed,
+		},
+		{
+			name:  "truncated payload",
+			data:  withEnvelope(func(env *Envelope) { env.Payload = env.Payload[:len(env.Payload)-1] }),
+			match: ErrMalformed,
+		},
+		{
+			name: "field longer than the message",
+			data: withEnvelope(func(env *Envelope) {
+				env.Payload = protowire.AppendTag(nil, 1, protowire.BytesType)
+				env.Payload = protowire.AppendVarint(env.Payload, 1<<20)
+				env.Payload = append(env.Payload, "hash"...)
+			}),
+			match: ErrMalformed,
+		},
+		{
+			name: "varint overflow",
+			data: withEnvelope(func(env *Envelope) {
+				env.Type = TypeStatus
+				env.Payload = protowire.AppendTag(nil, 1, protowire.VarintType)
+				env.Payload = append(env.Payload, bytes.Repeat([]byte{0xff}, 10)...)
+				env.Payload = append(env.Payload, 0x01)
+			}),
+			match: ErrMalformed,
+		},
+		{
+			name: "wrong wire type",
+			data: withEnvelope(func(env *Envelope) {
+				env.Type = TypeStatus
+				env.Payload = protowire.AppendTag(nil, 2, protowire.VarintType)
+				env.Payload = protowire.AppendVarint(env.Payload, 1)
+			}),
+			match: ErrMalformed,
+		},
+		{
+			name: "malformed nested transaction",
+			data: withEnvelope(func(env *Envelope) {
+				tx := MarshalTransaction(testTransaction())
+
+				env.Type = TypeAnnounceTx
+				env.Payload = appendMessage(nil, 1, tx[:len(tx)-1])
+			}),
+			match: ErrMalformed,
+		},
+		{
+			name: "transaction announcement without transaction",
+			data: withEnvelope(func(env *Envelope) {
+				env.Type = TypeAnnounceTx
+				env.Payload = nil
+			}),
+			match: ErrMalformed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, msg, err := Decode(testNetwork, tt.data)
+			if !errors.Is(err, tt.match) {
+				t.Fatalf("Decode() error = %v, want %v", err, tt.match)
+			}
+
+			if msg != nil {
+				t.Fatalf("Decode() returned %+v along with the error", msg)
+			}
+		})
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	for _, msg := range testMessages() {
+		f.Add(Encode(testNetwork, msg))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, msg, err := Decode(testNetwork, data)
+		if err != nil {
+			return
+		}
+
+		// Whatever decodes must encode back to a message decoding to the
+		// same content
+		_, again, err := Decode(testNetwork, Encode(testNetwork, msg))
+		if err != nil {
+			t.Fatalf("decoding the encoded %+v: %s", msg, err)
+		}
+
+		if !bytes.Equal(again.Marshal(), msg.Marshal()) {
+			t.Fatalf("message changed after encoding it again: %+v, then %+v", msg, again)
+		}
+	})
+}